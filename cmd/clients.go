@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/amaliebjorgen/fabricant/pkg/auth"
+	"github.com/amaliebjorgen/fabricant/pkg/config"
+	"github.com/amaliebjorgen/fabricant/pkg/devops"
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/amaliebjorgen/fabricant/pkg/github"
+	"github.com/amaliebjorgen/fabricant/pkg/parameters"
+	"github.com/amaliebjorgen/fabricant/pkg/transport"
+)
+
+// clients bundles the API clients shared by the subcommands and the TUI, together with the
+// profile they were configured from.
+type clients struct {
+	auth   *auth.Authenticator
+	fabric *fabric.Client
+	devops *devops.Client
+	github *github.Client
+
+	// parameters are applied to new feature workspaces for environment, if set.
+	parameters     *parameters.File
+	parametersFile string
+	environment    string
+
+	profileName string
+	profile     *config.Profile
+}
+
+// deviceCodePrompt, if set, shows device code sign-in instructions instead of stderr.
+var deviceCodePrompt func(message string)
+
+// authOptions resolves the authentication settings from, in order of precedence, the flags,
+// FABRICANT_* environment variables, the profile and the AZURE_* environment.
+func authOptions(p *config.Profile) (auth.Options, error) {
+	opts := auth.OptionsFromEnv()
+	opts.DeviceCodePrompt = deviceCodePrompt
+	credential, profileCloud := "", ""
+	if p != nil {
+		credential, profileCloud = p.Credential, p.Cloud
+		opts.TenantId = firstNonEmpty(p.TenantId, opts.TenantId)
+		opts.ClientId = firstNonEmpty(p.ClientId, opts.ClientId)
+	}
+
+	t, err := auth.ParseCredentialType(firstNonEmpty(authType, os.Getenv("FABRICANT_AUTH"), credential))
+	if err != nil {
+		return opts, err
+	}
+	opts.Credential = t
+	opts.TenantId = firstNonEmpty(tenantId, os.Getenv("FABRICANT_TENANT"), opts.TenantId)
+	opts.ClientId = firstNonEmpty(authClientId, opts.ClientId)
+	opts.CertificatePath = firstNonEmpty(authCertificate, opts.CertificatePath)
+	opts.Cloud, err = auth.ParseCloud(firstNonEmpty(cloudName, os.Getenv("FABRICANT_CLOUD"), profileCloud))
+	return opts, err
+}
+
+// fabricOptions resolves the Fabric endpoint and token scope.
+func fabricOptions(p *config.Profile, hc *http.Client) []fabric.Option {
+	opts := []fabric.Option{fabric.WithHTTPClient(hc)}
+
+	profileURL, profileScope := "", ""
+	if p != nil {
+		profileURL, profileScope = p.FabricURL, p.FabricScope
+	}
+	if u := firstNonEmpty(fabricURL, os.Getenv("FABRICANT_FABRIC_URL"), profileURL); u != "" {
+		opts = append(opts, fabric.WithBaseURL(u))
+	}
+	if scope := firstNonEmpty(os.Getenv("FABRICANT_FABRIC_SCOPE"), profileScope); scope != "" {
+		opts = append(opts, fabric.WithScope(scope))
+	}
+	return opts
+}
+
+// devopsOptions resolves the Azure DevOps URL, token scope and personal access token. PATs are
+// only read from the environment so they never end up in the config file.
+func devopsOptions(p *config.Profile, hc *http.Client) []devops.Option {
+	opts := []devops.Option{devops.WithHTTPClient(hc)}
+
+	profileURL, profileScope := "", ""
+	if p != nil {
+		profileURL, profileScope = p.DevOpsURL, p.DevOpsScope
+	}
+	if u := firstNonEmpty(devopsURL, os.Getenv("FABRICANT_DEVOPS_URL"), profileURL); u != "" {
+		opts = append(opts, devops.WithBaseURL(u))
+	}
+	if scope := firstNonEmpty(os.Getenv("FABRICANT_DEVOPS_SCOPE"), profileScope); scope != "" {
+		opts = append(opts, devops.WithScope(scope))
+	}
+	if pat := firstNonEmpty(os.Getenv("FABRICANT_DEVOPS_PAT"), os.Getenv("AZURE_DEVOPS_EXT_PAT")); pat != "" {
+		opts = append(opts, devops.WithPersonalAccessToken(pat))
+	}
+	return opts
+}
+
+// githubOptions resolves the GitHub endpoint and credentials from the environment: a token
+// from FABRICANT_GITHUB_TOKEN or GITHUB_TOKEN, or a GitHub App from FABRICANT_GITHUB_APP_ID,
+// FABRICANT_GITHUB_APP_PRIVATE_KEY (a PEM file) and optionally FABRICANT_GITHUB_APP_INSTALLATION_ID.
+func githubOptions(hc *http.Client) ([]github.Option, error) {
+	opts := []github.Option{github.WithHTTPClient(hc)}
+
+	if u := os.Getenv("FABRICANT_GITHUB_URL"); u != "" {
+		opts = append(opts, github.WithBaseURL(u))
+	}
+	if replayDir != "" {
+		// Like the Azure token, the GitHub credentials are never checked when replaying.
+		return append(opts, github.WithToken("replay")), nil
+	}
+	if appId := os.Getenv("FABRICANT_GITHUB_APP_ID"); appId != "" {
+		pemBytes, err := os.ReadFile(os.Getenv("FABRICANT_GITHUB_APP_PRIVATE_KEY"))
+		if err != nil {
+			return nil, fmt.Errorf("reading github app private key: %w", err)
+		}
+		key, err := github.ParsePrivateKey(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		installationId, err := github.ParseInstallationId(os.Getenv("FABRICANT_GITHUB_APP_INSTALLATION_ID"))
+		if err != nil {
+			return nil, err
+		}
+		return append(opts, github.WithApp(appId, key, installationId)), nil
+	}
+	if token := firstNonEmpty(os.Getenv("FABRICANT_GITHUB_TOKEN"), os.Getenv("GITHUB_TOKEN")); token != "" {
+		opts = append(opts, github.WithToken(token))
+	}
+	return opts, nil
+}
+
+// defaultEnvironment is the parameter file environment feature workspaces deploy to.
+const defaultEnvironment = "feature"
+
+// loadParameters reads the parameter file from the flag, $FABRICANT_PARAMETERS or the profile,
+// and resolves the environment to apply. It returns the file's path alongside its rules.
+func loadParameters(p *config.Profile) (*parameters.File, string, string, error) {
+	profileFile, profileEnv := "", ""
+	if p != nil {
+		profileFile, profileEnv = p.ParameterFile, p.Environment
+	}
+	env := firstNonEmpty(environment, os.Getenv("FABRICANT_ENVIRONMENT"), profileEnv, defaultEnvironment)
+	path := firstNonEmpty(parameterFile, os.Getenv("FABRICANT_PARAMETERS"), profileFile)
+	if path == "" {
+		return nil, "", env, nil
+	}
+	f, err := parameters.Load(path)
+	return f, path, env, err
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// baseTransport returns the transport beneath retries: the network, or a cassette when
+// recording or replaying, logged if logging is enabled.
+func baseTransport() (http.RoundTripper, error) {
+	var base http.RoundTripper = http.DefaultTransport
+	var err error
+	switch {
+	case replayDir != "":
+		base, err = transport.NewReplayTransport(replayDir)
+	case recordDir != "":
+		base, err = transport.NewRecordTransport(nil, recordDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if logger != nil {
+		base = transport.NewLogTransport(base, logger)
+	}
+	return base, nil
+}
+
+func newClients() (*clients, error) {
+	name, profile, err := activeProfile()
+	if err != nil {
+		return nil, err
+	}
+	params, paramsFile, env, err := loadParameters(profile)
+	if err != nil {
+		return nil, err
+	}
+	base, err := baseTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	// Replayed sessions never reach the services, so they need no credentials.
+	var a *auth.Authenticator
+	var tokens auth.TokenSource = auth.StaticToken("replay")
+	if replayDir == "" {
+		opts, err := authOptions(profile)
+		if err != nil {
+			return nil, err
+		}
+		a, err = auth.NewAuthenticatorWithOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		tokens = a
+	}
+
+	policy := transport.DefaultRetryPolicy
+	policy.MaxRetries = maxRetries
+	hc := &http.Client{Transport: transport.NewRetryTransport(base, policy)}
+	ghOpts, err := githubOptions(hc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clients{
+		auth:           a,
+		fabric:         fabric.NewClient(tokens, fabricOptions(profile, hc)...),
+		devops:         devops.NewClient(tokens, devopsOptions(profile, hc)...),
+		github:         github.NewClient(ghOpts...),
+		parameters:     params,
+		parametersFile: paramsFile,
+		environment:    env,
+		profileName:    name,
+		profile:        profile,
+	}, nil
+}
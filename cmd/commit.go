@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/spf13/cobra"
+)
+
+// maxCommitComment is the longest commit comment Fabric accepts.
+const maxCommitComment = 300
+
+var (
+	commitWorkspace string
+	commitMessage   string
+	commitItems     []string
+)
+
+var commitCmd = &cobra.Command{
+	Use:   "commit",
+	Short: "Commit workspace changes to git",
+	Long: `Commits the uncommitted changes in a workspace to its git branch, like the portal's source
+control panel. Without --message, lists the pending changes instead. Use --item to commit only
+some items; items are given by id, display name or "<name>.<type>".`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		out := cmd.OutOrStdout()
+
+		if len(commitMessage) > maxCommitComment {
+			return fmt.Errorf("commit message is %d characters, the limit is %d", len(commitMessage), maxCommitComment)
+		}
+		c, err := newClients()
+		if err != nil {
+			return err
+		}
+		ws, err := resolveWorkspace(ctx, c.fabric, commitWorkspace)
+		if err != nil {
+			return err
+		}
+		status, err := c.fabric.GetGitStatus(ctx, ws.Id)
+		if err != nil {
+			return fmt.Errorf("getting git status of %s: %w", ws.DisplayName, err)
+		}
+		changes := pendingChanges(status)
+		if len(changes) == 0 {
+			fmt.Fprintf(out, "No changes to commit in workspace %q.\n", ws.DisplayName)
+			return nil
+		}
+		if len(commitItems) > 0 {
+			if changes, err = selectChanges(changes, commitItems); err != nil {
+				return err
+			}
+		}
+
+		if err := printChanges(out, changes); err != nil {
+			return err
+		}
+		if commitMessage == "" {
+			fmt.Fprintf(out, "\nCommit them with: fabricant commit --workspace %q -m <message>\n", ws.DisplayName)
+			return nil
+		}
+
+		if err := commitToGit(ctx, c.fabric, ws.Id, status, changes, commitMessage, len(commitItems) == 0); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "\nCommitted %d change(s) in workspace %q.\n", len(changes), ws.DisplayName)
+		return nil
+	},
+}
+
+// pendingChanges returns the workspace-side changes in status, which are the ones that can be
+// committed.
+func pendingChanges(status *fabric.GitStatus) []fabric.ItemChange {
+	var changes []fabric.ItemChange
+	for _, c := range status.Changes {
+		if c.WorkspaceChange != "" {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// selectChanges returns the changes to the items named by id, display name or "<name>.<type>".
+func selectChanges(changes []fabric.ItemChange, items []string) ([]fabric.ItemChange, error) {
+	var selected []fabric.ItemChange
+	for _, name := range items {
+		var matches []fabric.ItemChange
+		for _, c := range changes {
+			m := c.ItemMetadata
+			if strings.EqualFold(m.ItemIdentifier.ObjectId, name) ||
+				strings.EqualFold(m.DisplayName, name) ||
+				strings.EqualFold(m.DisplayName+"."+m.ItemType, name) {
+				matches = append(matches, c)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("item %q has no changes to commit", name)
+		case 1:
+			selected = append(selected, matches[0])
+		default:
+			return nil, fmt.Errorf("item name %q is ambiguous (%d matches), use <name>.<type> or the item id instead", name, len(matches))
+		}
+	}
+	return selected, nil
+}
+
+// commitToGit commits changes with comment. With all set every pending change is committed,
+// which also covers changes made since status was read.
+func commitToGit(ctx context.Context, api fabric.API, workspaceId string, status *fabric.GitStatus, changes []fabric.ItemChange, comment string, all bool) error {
+	req := fabric.CommitToGitRequest{
+		Mode:          fabric.CommitModeAll,
+		WorkspaceHead: status.WorkspaceHead,
+		Comment:       comment,
+	}
+	if !all {
+		req.Mode = fabric.CommitModeSelective
+		for _, c := range changes {
+			req.Items = append(req.Items, c.ItemMetadata.ItemIdentifier)
+		}
+	}
+	if err := api.CommitToGit(ctx, workspaceId, req); err != nil {
+		return fmt.Errorf("committing to git: %w", err)
+	}
+	return nil
+}
+
+func printChanges(out io.Writer, changes []fabric.ItemChange) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANGE\tTYPE\tNAME\tCONFLICT")
+	for _, c := range changes {
+		conflict := ""
+		if c.ConflictType == fabric.ConflictTypeConflict {
+			conflict = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.WorkspaceChange, c.ItemMetadata.ItemType, c.ItemMetadata.DisplayName, conflict)
+	}
+	return w.Flush()
+}
+
+func init() {
+	commitCmd.Flags().StringVarP(&commitWorkspace, "workspace", "w", "", "Workspace id or display name")
+	commitCmd.Flags().StringVarP(&commitMessage, "message", "m", "", "Commit message")
+	commitCmd.Flags().StringSliceVar(&commitItems, "item", nil, "Commit only this item (repeatable)")
+	commitCmd.MarkFlagRequired("workspace")
+
+	rootCmd.AddCommand(commitCmd)
+}
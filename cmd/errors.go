@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/amaliebjorgen/fabricant/pkg/devops"
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/amaliebjorgen/fabricant/pkg/github"
+)
+
+// errorHint returns remediation advice for well-known API errors, followed by the request id
+// to quote to Microsoft support. It returns "" if there is nothing useful to add.
+func errorHint(err error) string {
+	var lines []string
+
+	var fabErr *fabric.APIError
+	var devErr *devops.APIError
+	var ghErr *github.APIError
+	switch {
+	case fabric.IsErrorCode(err, fabric.ErrorCodeWorkspaceNameAlreadyExists):
+		lines = append(lines, "A workspace with this name already exists. Choose a different workspace name.")
+	case fabric.IsErrorCode(err, fabric.ErrorCodeInsufficientPrivileges):
+		lines = append(lines, "Your account lacks permission for this operation. Creating feature workspaces requires Admin rights on the capacity and Contributor or higher on the parent workspace.")
+	case fabric.IsErrorCode(err, fabric.ErrorCodeWorkspaceNotFound):
+		lines = append(lines, "The workspace no longer exists or you no longer have access to it.")
+	case errors.Is(err, devops.ErrBranchAlreadyExists), errors.Is(err, github.ErrBranchAlreadyExists):
+		lines = append(lines, "The feature branch already exists. Choose a different branch name, or delete the existing branch first.")
+	case errors.As(err, &devErr) && devErr.StatusCode == http.StatusNonAuthoritativeInfo:
+		lines = append(lines, "Azure DevOps did not accept the personal access token. Check that it has not expired and has Code (Read & Write) scope.")
+	case errors.As(err, &fabErr) && fabErr.StatusCode == http.StatusUnauthorized,
+		errors.As(err, &devErr) && devErr.StatusCode == http.StatusUnauthorized:
+		lines = append(lines, "Authentication was rejected. Run `az login` again and make sure the right tenant is selected.")
+	case errors.As(err, &ghErr) && (ghErr.StatusCode == http.StatusUnauthorized || ghErr.StatusCode == http.StatusForbidden):
+		lines = append(lines, "GitHub rejected the credentials. Check that FABRICANT_GITHUB_TOKEN has Contents (Read & Write) permission on the repository, or that the GitHub App is installed on it.")
+	case errors.As(err, &ghErr) && ghErr.StatusCode == http.StatusNotFound:
+		lines = append(lines, "The repository was not found on GitHub. Check the workspace's git connection and that your token can access the repository.")
+	case errors.As(err, &fabErr) && fabErr.StatusCode == http.StatusTooManyRequests:
+		lines = append(lines, "Fabric is throttling requests. Wait a few minutes, or run fewer operations in parallel.")
+	case errors.As(err, &devErr) && devErr.TypeKey == "GitRepositoryNotFoundException":
+		lines = append(lines, "The repository was not found in Azure DevOps. Check the workspace's git connection and your access to the project.")
+	}
+
+	if errors.As(err, &fabErr) && fabErr.RequestId != "" {
+		lines = append(lines, "Fabric request id: "+fabErr.RequestId)
+	}
+	if errors.As(err, &devErr) && devErr.ActivityId != "" {
+		lines = append(lines, "Azure DevOps activity id: "+devErr.ActivityId)
+	}
+	if errors.As(err, &ghErr) && ghErr.RequestId != "" {
+		lines = append(lines, "GitHub request id: "+ghErr.RequestId)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/amaliebjorgen/fabricant/pkg/config"
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/amaliebjorgen/fabricant/pkg/workflow"
+	"github.com/spf13/cobra"
+)
+
+var featureCmd = &cobra.Command{
+	Use:   "feature",
+	Short: "Manage feature environments",
+}
+
+var (
+	featureParentWorkspace string
+	featureBranch          string
+	featureWorkspaceName   string
+	featureCapacity        string
+	featureRollback        bool
+)
+
+var featureCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a feature branch and workspace without the TUI",
+	Long: `Creates a feature branch from the parent workspace's git branch, creates a new Fabric
+workspace, connects it to the branch and updates it from git. Progress is written as plain
+lines, and the command exits non-zero if any step fails.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := interruptContext(cmd.Context())
+		defer stop()
+		out := cmd.OutOrStdout()
+
+		c, err := newClients()
+		if err != nil {
+			return err
+		}
+		store, err := workflow.DefaultRunStore()
+		if err != nil {
+			return err
+		}
+
+		parent, err := resolveWorkspace(ctx, c.fabric, featureParentWorkspace)
+		if err != nil {
+			return err
+		}
+		conn, err := c.fabric.GetGitConnection(ctx, parent.Id)
+		if err != nil {
+			return fmt.Errorf("failed to get git connection: %w", err)
+		}
+		if conn.GitProviderDetails == nil || conn.GitProviderDetails.GitProviderType == "" {
+			return fmt.Errorf("workspace %q does not have git integration (or unsupported provider)", parent.DisplayName)
+		}
+		parent.GitProviderDetails = conn.GitProviderDetails
+		if err := checkOrganization(c.profileName, c.profile, parent.GitProviderDetails); err != nil {
+			return err
+		}
+
+		rollback := featureRollback
+		if !cmd.Flags().Changed("rollback") {
+			// Pipelines cannot answer a prompt, so clean up by default only when nobody is
+			// at a terminal. Interactive users keep the partial environment to resume it.
+			rollback = !stdinIsTerminal()
+		}
+
+		wsName := featureWorkspaceName
+		if wsName == "" {
+			wsName = "Feature - " + featureBranch
+		}
+
+		res, err := workflow.CreateFeatureEnvironment(ctx, workflow.Options{
+			Fabric:            c.fabric,
+			DevOps:            c.devops,
+			GitHub:            c.github,
+			Parameters:        c.parameters,
+			ParametersFile:    c.parametersFile,
+			Environment:       c.environment,
+			Parent:            parent,
+			BranchName:        featureBranch,
+			WorkspaceName:     wsName,
+			CapacityId:        firstNonEmpty(featureCapacity, profileCapacityId(c.profile)),
+			RollbackOnFailure: rollback,
+			Store:             store,
+			OnEvent:           printEvents(out),
+		})
+		return reportResult(out, res, err)
+	},
+}
+
+// profileCapacityId returns the profile's default capacity, if any.
+func profileCapacityId(p *config.Profile) string {
+	if p == nil {
+		return ""
+	}
+	return p.Capacity
+}
+
+// printEvents returns an event handler that writes one plain line per step.
+func printEvents(out io.Writer) func(workflow.Event) {
+	return func(ev workflow.Event) {
+		if ev.Kind != workflow.EventStepStarted {
+			return
+		}
+		if ev.Skipped {
+			fmt.Fprintf(out, "==> %s (already done)\n", ev.Message)
+			return
+		}
+		fmt.Fprintf(out, "==> %s\n", ev.Message)
+	}
+}
+
+// reportResult prints the outcome of a feature workflow run and passes err through.
+func reportResult(out io.Writer, res workflow.Result, err error) error {
+	if err != nil {
+		switch {
+		case res.RunId == "":
+		case !res.RollbackStarted():
+			fmt.Fprintf(out, "Run %s did not finish. Continue it with: fabricant resume %s\n", res.RunId, res.RunId)
+		case res.NeedsRollback():
+			fmt.Fprintf(out, "Rollback of run %s did not finish. Complete it with: fabricant resume --rollback-only %s\n", res.RunId, res.RunId)
+		}
+		return err
+	}
+	fmt.Fprintf(out, "Workspace %q (%s) created on branch %s and synced successfully.\n", res.Workspace.DisplayName, res.Workspace.Id, res.BranchName)
+	return nil
+}
+
+// interruptContext returns a context cancelled by Ctrl+C or SIGTERM, e.g. when a pipeline is
+// cancelled, so a running workflow fails its current step, rolls back if asked to and journals
+// the failure instead of being killed. A second signal exits immediately.
+func interruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	return ctx, stop
+}
+
+// stdinIsTerminal reports whether standard input is an interactive terminal.
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// resolveWorkspace finds a workspace by id or by display name.
+func resolveWorkspace(ctx context.Context, fabricClient *fabric.Client, idOrName string) (*fabric.Workspace, error) {
+	workspaces, err := fabricClient.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing workspaces: %w", err)
+	}
+
+	var matches []fabric.Workspace
+	for _, ws := range workspaces {
+		if ws.Id == idOrName {
+			return &ws, nil
+		}
+		if strings.EqualFold(ws.DisplayName, idOrName) {
+			matches = append(matches, ws)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("workspace %q not found", idOrName)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("workspace name %q is ambiguous (%d matches), use the workspace id instead", idOrName, len(matches))
+	}
+}
+
+func init() {
+	featureCreateCmd.Flags().StringVar(&featureParentWorkspace, "parent-workspace", "", "Parent dev workspace id or display name")
+	featureCreateCmd.Flags().StringVar(&featureBranch, "branch", "", "Name of the feature branch to create")
+	featureCreateCmd.Flags().StringVar(&featureWorkspaceName, "workspace-name", "", `Name of the new workspace (default "Feature - <branch>")`)
+	featureCreateCmd.Flags().StringVar(&featureCapacity, "capacity", "", "Capacity id for the new workspace (default: the profile's capacity, then the parent's)")
+	featureCreateCmd.Flags().BoolVar(&featureRollback, "rollback", false, "Delete the created branch and workspace if a later step fails (default true when stdin is not a terminal)")
+	featureCreateCmd.MarkFlagRequired("parent-workspace")
+	featureCreateCmd.MarkFlagRequired("branch")
+
+	featureCmd.AddCommand(featureCreateCmd)
+	rootCmd.AddCommand(featureCmd)
+}
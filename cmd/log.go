@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// logger receives HTTP traces when --debug or $FABRICANT_LOG is set, and is nil otherwise.
+var logger *slog.Logger
+
+// defaultLogPath returns the --debug log file, e.g. ~/.config/fabricant/fabricant.log.
+func defaultLogPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating config directory: %w", err)
+	}
+	return filepath.Join(dir, "fabricant", "fabricant.log"), nil
+}
+
+// setupLogging opens the log file selected by $FABRICANT_LOG, or the default one if --debug
+// is set. Logs go to a file rather than stderr so they don't corrupt the TUI.
+func setupLogging() error {
+	path := os.Getenv("FABRICANT_LOG")
+	if path == "" {
+		if !debug {
+			return nil
+		}
+		var err error
+		if path, err = defaultLogPath(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	logger = slog.New(slog.NewJSONHandler(f, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	slog.SetDefault(logger)
+	return nil
+}
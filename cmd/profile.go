@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/amaliebjorgen/fabricant/pkg/auth"
+	"github.com/amaliebjorgen/fabricant/pkg/config"
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/spf13/cobra"
+)
+
+// profileName is the --profile flag.
+var profileName string
+
+// activeProfile returns the profile selected by --profile, $FABRICANT_PROFILE or the config's
+// current profile, in that order. It returns a nil profile if none is selected.
+func activeProfile() (string, *config.Profile, error) {
+	name := profileName
+	if name == "" {
+		name = os.Getenv("FABRICANT_PROFILE")
+	}
+
+	path, err := config.DefaultPath()
+	if err != nil {
+		return "", nil, err
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		name = cfg.CurrentProfile
+	}
+	if name == "" {
+		return "", nil, nil
+	}
+	p, err := cfg.Profile(name)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, p, nil
+}
+
+// checkOrganization guards against using a workspace from another customer's Azure DevOps
+// organization than the one the profile is bound to.
+func checkOrganization(name string, p *config.Profile, details *fabric.GitProviderDetails) error {
+	if p == nil || p.DevOpsOrganization == "" || details == nil || details.GitProviderType != fabric.GitProviderAzureDevOps {
+		return nil
+	}
+	if !strings.EqualFold(p.DevOpsOrganization, details.OrganizationName) {
+		return fmt.Errorf("workspace is connected to Azure DevOps organization %q, but profile %q is bound to %q", details.OrganizationName, name, p.DevOpsOrganization)
+	}
+	return nil
+}
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named tenant profiles",
+	Long: `Profiles bind a tenant, credential, Azure DevOps organization and default capacity under a
+name. The current profile is used unless --profile or $FABRICANT_PROFILE selects another.
+
+Settings are applied in the order: flags, FABRICANT_* environment variables, the active
+profile, then AZURE_* environment variables.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.DefaultPath()
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+		if len(cfg.Profiles) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No profiles. Create one with: fabricant profile set <name> --tenant <tenant-id>")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CURRENT\tNAME\tTENANT\tCREDENTIAL\tCLOUD\tDEVOPS ORG\tCAPACITY")
+		for _, name := range cfg.ProfileNames() {
+			p := cfg.Profiles[name]
+			current := ""
+			if name == cfg.CurrentProfile {
+				current = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", current, name, p.TenantId, p.Credential, p.Cloud, p.DevOpsOrganization, p.Capacity)
+		}
+		return w.Flush()
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Make a profile the current one",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.DefaultPath()
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+		if _, err := cfg.Profile(args[0]); err != nil {
+			return err
+		}
+		cfg.CurrentProfile = args[0]
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Switched to profile %q.\n", args[0])
+		return nil
+	},
+}
+
+var (
+	profileDevOpsOrg   string
+	profileCapacity    string
+	profileFabricScope string
+	profileDevOpsScope string
+)
+
+var profileSetCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: "Create or update a profile",
+	Long: `Creates or updates a profile from the --tenant, --auth, --client-id, --cloud, --fabric-url,
+--devops-url, --parameters and --environment flags and the flags below. Flags that are not given keep their current value.
+
+For a sovereign cloud, set --cloud and --fabric-url; the Fabric token scope follows the
+endpoint's host unless --fabric-scope is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.DefaultPath()
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+
+		p := &config.Profile{}
+		if existing, err := cfg.Profile(args[0]); err == nil {
+			p = existing
+		}
+		flags := cmd.Flags()
+		if flags.Changed("tenant") {
+			p.TenantId = tenantId
+		}
+		if flags.Changed("auth") {
+			if _, err := auth.ParseCredentialType(authType); err != nil {
+				return err
+			}
+			p.Credential = authType
+		}
+		if flags.Changed("client-id") {
+			p.ClientId = authClientId
+		}
+		if flags.Changed("cloud") {
+			if _, err := auth.ParseCloud(cloudName); err != nil {
+				return err
+			}
+			p.Cloud = cloudName
+		}
+		if flags.Changed("fabric-url") {
+			p.FabricURL = fabricURL
+		}
+		if flags.Changed("fabric-scope") {
+			p.FabricScope = profileFabricScope
+		}
+		if flags.Changed("devops-url") {
+			p.DevOpsURL = devopsURL
+		}
+		if flags.Changed("parameters") {
+			// Profiles are used from any directory.
+			abs, err := filepath.Abs(parameterFile)
+			if err != nil {
+				return err
+			}
+			p.ParameterFile = abs
+		}
+		if flags.Changed("environment") {
+			p.Environment = environment
+		}
+		if flags.Changed("devops-scope") {
+			p.DevOpsScope = profileDevOpsScope
+		}
+		if flags.Changed("devops-org") {
+			p.DevOpsOrganization = profileDevOpsOrg
+		}
+		if flags.Changed("capacity") {
+			p.Capacity = profileCapacity
+		}
+
+		cfg.SetProfile(args[0], p)
+		if cfg.CurrentProfile == "" {
+			cfg.CurrentProfile = args[0]
+		}
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Saved profile %q.\n", args[0])
+		return nil
+	},
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.DefaultPath()
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+		if err := cfg.DeleteProfile(args[0]); err != nil {
+			return err
+		}
+		return cfg.Save(path)
+	},
+}
+
+func init() {
+	profileSetCmd.Flags().StringVar(&profileDevOpsOrg, "devops-org", "", "Azure DevOps organization the profile's workspaces must use")
+	profileSetCmd.Flags().StringVar(&profileCapacity, "capacity", "", "Default capacity id for new feature workspaces")
+	profileSetCmd.Flags().StringVar(&profileFabricScope, "fabric-scope", "", "OAuth scope for Fabric tokens (default $FABRICANT_FABRIC_SCOPE, or derived from the Fabric URL)")
+	profileSetCmd.Flags().StringVar(&profileDevOpsScope, "devops-scope", "", "OAuth scope for Azure DevOps tokens (default $FABRICANT_DEVOPS_SCOPE)")
+
+	profileCmd.AddCommand(profileListCmd, profileUseCmd, profileSetCmd, profileDeleteCmd)
+	rootCmd.AddCommand(profileCmd)
+}
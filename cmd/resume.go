@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/amaliebjorgen/fabricant/pkg/workflow"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resumeRollback     bool
+	resumeRollbackOnly bool
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [run-id]",
+	Short: "Continue an unfinished feature creation run",
+	Long: `Continues a feature creation run from the last step that completed, using the run state
+saved by "feature create" and the TUI. The run uses the parameter file and environment it was
+started with. Without a run id, lists the unfinished runs.
+
+Runs whose rollback started but did not finish cannot be resumed, as the workspace or branch
+they created may be gone. Finish their rollback with --rollback-only instead.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		store, err := workflow.DefaultRunStore()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			runs, err := store.Unfinished()
+			if err != nil {
+				return err
+			}
+			partial, err := store.PartiallyRolledBack()
+			if err != nil {
+				return err
+			}
+			if len(runs) == 0 && len(partial) == 0 {
+				fmt.Fprintln(out, "No unfinished runs.")
+				return nil
+			}
+			if len(runs) > 0 {
+				if err := printRuns(out, runs); err != nil {
+					return err
+				}
+			}
+			if len(partial) > 0 {
+				if len(runs) > 0 {
+					fmt.Fprintln(out)
+				}
+				fmt.Fprintln(out, "Partially rolled back (finish with: fabricant resume --rollback-only <run-id>):")
+				return printRuns(out, partial)
+			}
+			return nil
+		}
+
+		ctx, stop := interruptContext(cmd.Context())
+		defer stop()
+
+		run, err := store.Load(args[0])
+		if err != nil {
+			return err
+		}
+		c, err := newClients()
+		if err != nil {
+			return err
+		}
+
+		if resumeRollbackOnly {
+			fmt.Fprintf(out, "Rolling back run %s (branch %s, workspace %q)\n", run.Id, run.BranchName, run.WorkspaceName)
+			res, err := workflow.RollbackRun(ctx, workflow.Options{
+				Fabric:  c.fabric,
+				DevOps:  c.devops,
+				GitHub:  c.github,
+				Store:   store,
+				OnEvent: printEvents(out),
+			}, run)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Run %s rolled back; branch %s and its workspace are gone.\n", run.Id, res.BranchName)
+			return nil
+		}
+		if run.PartiallyRolledBack() {
+			return fmt.Errorf("run %s was partially rolled back and cannot be resumed; finish the rollback with: fabricant resume --rollback-only %s", run.Id, run.Id)
+		}
+
+		fmt.Fprintf(out, "Resuming run %s (branch %s, workspace %q)\n", run.Id, run.BranchName, run.WorkspaceName)
+		res, err := workflow.ResumeFeatureEnvironment(ctx, workflow.Options{
+			Fabric:            c.fabric,
+			DevOps:            c.devops,
+			GitHub:            c.github,
+			RollbackOnFailure: resumeRollback,
+			Store:             store,
+			OnEvent:           printEvents(out),
+		}, run)
+		return reportResult(out, res, err)
+	},
+}
+
+// printRuns writes a table of runs.
+func printRuns(out io.Writer, runs []*workflow.Run) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN ID\tSTARTED\tBRANCH\tWORKSPACE\tLAST STEP\tERROR")
+	for _, r := range runs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Id, r.StartedAt.Format("2006-01-02 15:04"), r.BranchName, r.WorkspaceName, r.LastStep(), r.Error)
+	}
+	return w.Flush()
+}
+
+func init() {
+	resumeCmd.Flags().BoolVar(&resumeRollback, "rollback", false, "Delete the created branch and workspace if a step fails again")
+	resumeCmd.Flags().BoolVar(&resumeRollbackOnly, "rollback-only", false, "Delete what the run created instead of resuming it")
+	rootCmd.AddCommand(resumeCmd)
+}
@@ -3,14 +3,32 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/amaliebjorgen/fabricant/pkg/auth"
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/amaliebjorgen/fabricant/pkg/transport"
 	"github.com/spf13/cobra"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "fabricant",
-	Short: "Fabricant: Git Workflow TUI for Microsoft Fabric and Azure DevOps",
-	Long:  `A Terminal User Interface to help manage feature workspaces and branches in MS Fabric and Azure DevOps.`,
+	Short: "Fabricant: Git Workflow TUI for Microsoft Fabric, Azure DevOps and GitHub",
+	Long: `A Terminal User Interface to help manage feature workspaces and branches in MS Fabric and Azure DevOps or GitHub.
+
+By default fabricant authenticates with the first credential that works: a service principal
+(AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET or AZURE_CLIENT_CERTIFICATE_PATH),
+workload identity federation (AZURE_FEDERATED_TOKEN_FILE), the Azure CLI, then managed
+identity. Use --auth to pick one explicitly, including devicecode and browser sign-in.
+
+Branches in GitHub repositories are managed with FABRICANT_GITHUB_TOKEN (or GITHUB_TOKEN), or
+as a GitHub App with FABRICANT_GITHUB_APP_ID, FABRICANT_GITHUB_APP_PRIVATE_KEY and optionally
+FABRICANT_GITHUB_APP_INSTALLATION_ID. Set FABRICANT_GITHUB_URL for GitHub Enterprise Server.`,
+	// Execute prints errors itself, together with remediation hints.
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return setupLogging()
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Launch the TUI as the default behavior
 		StartUI()
@@ -23,10 +41,51 @@ func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		if hint := errorHint(err); hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
 		os.Exit(1)
 	}
 }
 
+var (
+	maxRetries      int
+	tenantId        string
+	authType        string
+	authClientId    string
+	authCertificate string
+	devopsURL       string
+	cloudName       string
+	fabricURL       string
+	parameterFile   string
+	environment     string
+	debug           bool
+	recordDir       string
+	replayDir       string
+)
+
 func init() {
 	// Flags and configuration settings can be defined here
+	rootCmd.PersistentFlags().IntVar(&maxRetries, "max-retries", transport.DefaultRetryPolicy.MaxRetries, "Retries for throttled or transiently failing API requests (0 disables retries)")
+
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Profile to use instead of the current one (default $FABRICANT_PROFILE)")
+	rootCmd.PersistentFlags().StringVar(&tenantId, "tenant", "", "Microsoft Entra tenant id to authenticate against (default $FABRICANT_TENANT)")
+
+	var types []string
+	for _, t := range auth.CredentialTypes {
+		types = append(types, string(t))
+	}
+	rootCmd.PersistentFlags().StringVar(&authType, "auth", "", "Credential type: "+strings.Join(types, ", ")+` (default "chain", or $FABRICANT_AUTH)`)
+	rootCmd.PersistentFlags().StringVar(&authClientId, "client-id", "", "Service principal, managed identity or public client id (default $AZURE_CLIENT_ID)")
+	rootCmd.PersistentFlags().StringVar(&devopsURL, "devops-url", "", `Azure DevOps URL template, e.g. "https://{organization}.visualstudio.com" (default $FABRICANT_DEVOPS_URL). Set FABRICANT_DEVOPS_PAT or AZURE_DEVOPS_EXT_PAT to use a personal access token`)
+	rootCmd.PersistentFlags().StringVar(&authCertificate, "client-certificate", "", "Path to a PEM or PKCS#12 service principal certificate (default $AZURE_CLIENT_CERTIFICATE_PATH)")
+	rootCmd.PersistentFlags().StringVar(&cloudName, "cloud", "", "Microsoft Entra cloud: "+strings.Join(auth.CloudNames, ", ")+` or an authority URL (default "public", or $FABRICANT_CLOUD)`)
+	rootCmd.PersistentFlags().StringVar(&fabricURL, "fabric-url", "", `Fabric REST API endpoint (default "`+fabric.BaseURL+`", or $FABRICANT_FABRIC_URL)`)
+
+	rootCmd.PersistentFlags().StringVar(&parameterFile, "parameters", "", "parameter.yml find-and-replace rules to apply to new feature workspaces (default $FABRICANT_PARAMETERS)")
+	rootCmd.PersistentFlags().StringVar(&environment, "environment", "", `Environment whose replacements --parameters applies (default "`+defaultEnvironment+`", or $FABRICANT_ENVIRONMENT)`)
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Log every API request, with request ids and redacted bodies, to fabricant.log in the config directory (or to $FABRICANT_LOG)")
+	rootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "Record every API request and response to this directory, with credentials redacted")
+	rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "Serve API responses from a directory written by --record instead of calling the services")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
 }
@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
-	"time"
 
 	"github.com/amaliebjorgen/fabricant/pkg/auth"
+	"github.com/amaliebjorgen/fabricant/pkg/config"
 	"github.com/amaliebjorgen/fabricant/pkg/devops"
 	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/amaliebjorgen/fabricant/pkg/github"
+	"github.com/amaliebjorgen/fabricant/pkg/parameters"
+	"github.com/amaliebjorgen/fabricant/pkg/workflow"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -22,6 +25,8 @@ import (
 func StartUI() {
 	m := initialModel()
 	p := tea.NewProgram(m, tea.WithAltScreen())
+	// stderr is hidden behind the alt screen, so sign-in instructions are shown in the UI.
+	deviceCodePrompt = func(message string) { p.Send(deviceCodeMsg{message}) }
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error starting UI: %v\n", err)
 		os.Exit(1)
@@ -42,12 +47,20 @@ type sessionState int
 
 const (
 	stateInit sessionState = iota
+	stateResumePrompt
 	stateLoadingWorkspaces
 	stateSelectWorkspace
+	stateLoadingItems
+	stateBrowseItems
+	stateLoadingChanges
+	stateSelectChanges
+	stateEnterCommitMessage
+	stateCommitting
 	stateLoadingGit
 	stateEnterBranch
 	stateEnterWorkspace
 	stateExecuting
+	stateRollingBack
 	stateDone
 	stateError
 )
@@ -56,23 +69,52 @@ type model struct {
 	state          sessionState
 	err            error
 	successMsg     string
-	executionInfos []string
+	executionSteps []executionStep
+	executionCh    chan tea.Msg
+	// executionResult is kept after a failed run so its resources can be rolled back.
+	executionResult *workflow.Result
 
 	authClient   *auth.Authenticator
 	fabricClient *fabric.Client
 	devopsClient *devops.Client
+	githubClient *github.Client
+	parameters   *parameters.File
+	// parametersFile is the path parameters was loaded from, journaled with each run.
+	parametersFile string
+	environment    string
+	runStore       *workflow.RunStore
+	profileName    string
+	profile        *config.Profile
 
 	// UI Components
 	spinner      spinner.Model
 	workspaceLst list.Model
 	branchInput  textinput.Model
 	wsInput      textinput.Model
+	itemLst      list.Model
+	changeLst    list.Model
+	commitInput  textinput.Model
 
 	// Data
 	workspaces           []fabric.Workspace
 	selectedDevWorkspace *fabric.Workspace
 	newBranchName        string
 	newWorkspaceName     string
+	// pendingRun is an unfinished run found at startup; resumeRun is set once the user resumes it.
+	pendingRun *workflow.Run
+	resumeRun  *workflow.Run
+
+	// browseWorkspace is the workspace shown in the item browser.
+	browseWorkspace fabric.Workspace
+	// itemStatus reports the outcome of the last item browser action.
+	itemStatus string
+	// commitWorkspace and commitStatus are the workspace and git status on the commit screen.
+	commitWorkspace fabric.Workspace
+	commitStatus    *fabric.GitStatus
+	// commitFrom is the screen the commit screen returns to.
+	commitFrom sessionState
+	// deviceCode holds device code sign-in instructions while a request waits on them.
+	deviceCode string
 }
 
 func initialModel() model {
@@ -91,6 +133,9 @@ func initialModel() model {
 	lst := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
 	lst.Title = "Select Parent Dev Workspace"
 	lst.SetShowStatusBar(false)
+	lst.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{createKey, browseKey, commitKey}
+	}
 
 	return model{
 		state:        stateInit,
@@ -98,6 +143,9 @@ func initialModel() model {
 		workspaceLst: lst,
 		branchInput:  bi,
 		wsInput:      wsi,
+		itemLst:      newItemList(),
+		changeLst:    newChangeList(),
+		commitInput:  newCommitInput(),
 	}
 }
 
@@ -109,7 +157,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
 
+	switch msg.(type) {
+	case deviceCodeMsg, spinner.TickMsg, tea.KeyMsg, tea.WindowSizeMsg:
+	default:
+		// Every other message answers a request, so any sign-in it waited for is complete.
+		m.deviceCode = ""
+	}
+
 	switch msg := msg.(type) {
+	case deviceCodeMsg:
+		m.deviceCode = msg.message
+		return m, nil
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" {
 			return m, tea.Quit
@@ -117,6 +175,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		h, v := lipgloss.NewStyle().Margin(1, 2).GetFrameSize()
 		m.workspaceLst.SetSize(msg.Width-h, msg.Height-v)
+		m.resizeItemBrowser(msg.Width-h, msg.Height-v-1)
+		m.changeLst.SetSize(msg.Width-h, msg.Height-v)
 	case errMsg:
 		m.err = msg.err
 		m.state = stateError
@@ -125,6 +185,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.authClient = msg.auth
 		m.fabricClient = msg.fabric
 		m.devopsClient = msg.devops
+		m.githubClient = msg.github
+		m.parameters = msg.parameters
+		m.parametersFile = msg.parametersFile
+		m.environment = msg.environment
+		m.runStore = msg.runStore
+		m.profileName = msg.profileName
+		m.profile = msg.profile
+		if m.profileName != "" {
+			m.workspaceLst.Title += " (profile: " + m.profileName + ")"
+		}
+		if msg.pendingRun != nil {
+			m.pendingRun = msg.pendingRun
+			m.state = stateResumePrompt
+			return m, nil
+		}
 		m.state = stateLoadingWorkspaces
 		return m, m.fetchWorkspacesCmd
 	case workspacesMsg:
@@ -136,9 +211,37 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.workspaceLst.SetItems(items)
 		m.state = stateSelectWorkspace
 		return m, nil
+	case itemsMsg:
+		m.setItems(msg)
+		m.state = stateBrowseItems
+		return m, nil
+	case changesMsg:
+		m.setChanges(msg)
+		m.state = stateSelectChanges
+		return m, nil
+	case commitDoneMsg:
+		m.successMsg = fmt.Sprintf("Committed %d change(s) in workspace %s.", msg.count, m.commitWorkspace.DisplayName)
+		m.state = stateDone
+		return m, tea.Quit
+	case itemActionMsg:
+		m.itemStatus = msg.msg
+		if msg.err != nil {
+			m.itemStatus = "Error: " + msg.err.Error()
+		}
+		return m, nil
 	case gitConnectionMsg:
 		if msg.details == nil || msg.details.GitProviderType == "" {
-			m.err = fmt.Errorf("selected workspace does not have git integration (or unsupported provider)")
+			m.err = fmt.Errorf("selected workspace does not have git integration")
+			m.state = stateError
+			return m, nil
+		}
+		if t := msg.details.GitProviderType; t != fabric.GitProviderAzureDevOps && t != fabric.GitProviderGitHub {
+			m.err = fmt.Errorf("unsupported git provider %q", t)
+			m.state = stateError
+			return m, nil
+		}
+		if err := checkOrganization(m.profileName, m.profile, msg.details); err != nil {
+			m.err = err
 			m.state = stateError
 			return m, nil
 		}
@@ -146,8 +249,23 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = stateEnterBranch
 		return m, textinput.Blink
 	case executionStepMsg:
-		m.executionInfos = append(m.executionInfos, msg.info)
+		m.applyExecutionEvent(msg.event)
+		return m, waitForExecution(m.executionCh)
+	case executionFailedMsg:
+		m.err = msg.err
+		m.executionResult = &msg.result
+		m.state = stateError
 		return m, nil
+	case rollbackDoneMsg:
+		m.executionResult = &msg.result
+		if msg.err != nil {
+			m.err = msg.err
+			m.state = stateError
+			return m, nil
+		}
+		m.successMsg = "Created workspace and branch were rolled back."
+		m.state = stateDone
+		return m, tea.Quit
 	case executionDoneMsg:
 		m.successMsg = msg.msg
 		m.state = stateDone
@@ -156,10 +274,50 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// State-specific updates
 	switch m.state {
-	case stateInit, stateLoadingWorkspaces, stateLoadingGit, stateExecuting:
+	case stateInit, stateLoadingWorkspaces, stateLoadingItems, stateLoadingChanges, stateCommitting, stateLoadingGit, stateExecuting, stateRollingBack:
 		m.spinner, cmd = m.spinner.Update(msg)
 		cmds = append(cmds, cmd)
 
+	case stateResumePrompt:
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "r":
+				run := m.pendingRun
+				parent := run.Parent
+				m.resumeRun = run
+				m.selectedDevWorkspace = &parent
+				m.newBranchName = run.BranchName
+				m.newWorkspaceName = run.WorkspaceName
+				return m.startExecution()
+			case "d":
+				if m.pendingRun.Result.NeedsRollback() {
+					// Discarding must not leave the run's workspace and branch behind.
+					m.state = stateRollingBack
+					m.executionCh = make(chan tea.Msg)
+					go m.rollbackRun(m.executionCh, m.pendingRun)
+					return m, tea.Batch(m.spinner.Tick, waitForExecution(m.executionCh))
+				}
+				if err := m.runStore.Delete(m.pendingRun.Id); err != nil {
+					m.err = fmt.Errorf("discarding run %s: %w", m.pendingRun.Id, err)
+					m.state = stateError
+					return m, nil
+				}
+				fallthrough
+			case "n", "enter":
+				m.pendingRun = nil
+				m.state = stateLoadingWorkspaces
+				return m, tea.Batch(m.spinner.Tick, m.fetchWorkspacesCmd)
+			}
+		}
+
+	case stateError:
+		if msg, ok := msg.(tea.KeyMsg); ok && msg.String() == "r" && m.canRollback() {
+			m.state = stateRollingBack
+			m.executionCh = make(chan tea.Msg)
+			go m.rollback(m.executionCh, *m.executionResult)
+			return m, tea.Batch(m.spinner.Tick, waitForExecution(m.executionCh))
+		}
+
 	case stateSelectWorkspace:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
@@ -170,10 +328,31 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, m.fetchGitConnectionCmd(m.selectedDevWorkspace.Id)
 				}
 			}
+			if key.Matches(msg, browseKey) && m.workspaceLst.FilterState() != list.Filtering {
+				if i, ok := m.workspaceLst.SelectedItem().(workspaceItem); ok {
+					m.browseWorkspace = i.workspace
+					m.state = stateLoadingItems
+					return m, tea.Batch(m.spinner.Tick, m.fetchItemsCmd(i.workspace))
+				}
+			}
+			if key.Matches(msg, commitKey) && m.workspaceLst.FilterState() != list.Filtering {
+				if i, ok := m.workspaceLst.SelectedItem().(workspaceItem); ok {
+					return m.startCommit(i.workspace)
+				}
+			}
 		}
 		m.workspaceLst, cmd = m.workspaceLst.Update(msg)
 		cmds = append(cmds, cmd)
 
+	case stateBrowseItems:
+		return m.updateItemBrowser(msg)
+
+	case stateSelectChanges:
+		return m.updateChangeSelection(msg)
+
+	case stateEnterCommitMessage:
+		return m.updateCommitMessage(msg)
+
 	case stateEnterBranch:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
@@ -196,8 +375,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if msg.String() == "enter" {
 				m.newWorkspaceName = m.wsInput.Value()
 				if m.newWorkspaceName != "" {
-					m.state = stateExecuting
-					return m, m.executeFlowCmd
+					return m.startExecution()
 				}
 			}
 		}
@@ -209,15 +387,60 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) View() string {
+	if m.deviceCode != "" {
+		return "\n" + quitStyle.Render(m.deviceCode) + "\n" + m.view()
+	}
+	return m.view()
+}
+
+func (m model) view() string {
 	if m.state == stateError {
-		return errorStyle.Render(fmt.Sprintf("\nError: %v\n\nPress ctrl+c to exit.", m.err))
+		hint := "Press ctrl+c to exit."
+		if m.canRollback() {
+			hint = "Press r to delete the created workspace and branch, or ctrl+c to exit."
+			if id := m.executionResult.RunId; id != "" && !m.executionResult.RollbackStarted() {
+				hint += "\nTo continue later instead, run: fabricant resume " + id
+			}
+		}
+		if h := errorHint(m.err); h != "" {
+			hint = h + "\n\n" + hint
+		}
+		return "\n" + m.executionView() + errorStyle.Render(fmt.Sprintf("\nError: %v\n\n%s", m.err, hint))
 	}
 
 	switch m.state {
 	case stateInit:
 		return fmt.Sprintf("\n %s Initializing clients...\n", m.spinner.View())
+	case stateResumePrompt:
+		r := m.pendingRun
+		last := "none"
+		if step := r.LastStep(); step != "" {
+			last = string(step)
+		}
+		info := fmt.Sprintf("\n  Found an unfinished run from %s:\n\n    Branch:    %s\n    Workspace: %s\n    Last step: %s\n",
+			r.StartedAt.Format("2006-01-02 15:04"), r.BranchName, r.WorkspaceName, last)
+		if r.Error != "" {
+			info += "    Error:     " + r.Error + "\n"
+		}
+		discard := "d to discard it"
+		if r.Result.NeedsRollback() {
+			discard = "d to delete its workspace and branch"
+		}
+		return info + quitStyle.Render("Press r to resume it, "+discard+", or n to start a new run.")
 	case stateLoadingWorkspaces:
 		return fmt.Sprintf("\n %s Loading workspaces from Fabric...\n", m.spinner.View())
+	case stateLoadingItems:
+		return fmt.Sprintf("\n %s Loading items in %s...\n", m.spinner.View(), m.browseWorkspace.DisplayName)
+	case stateBrowseItems:
+		return m.itemBrowserView()
+	case stateLoadingChanges:
+		return fmt.Sprintf("\n %s Checking changes in %s...\n", m.spinner.View(), m.commitWorkspace.DisplayName)
+	case stateSelectChanges:
+		return "\n" + m.changeLst.View()
+	case stateEnterCommitMessage:
+		return m.commitMessageView()
+	case stateCommitting:
+		return fmt.Sprintf("\n %s Committing to git...\n", m.spinner.View())
 	case stateLoadingGit:
 		return fmt.Sprintf("\n %s Checking Git configuration...\n", m.spinner.View())
 	case stateSelectWorkspace:
@@ -237,10 +460,11 @@ func (m model) View() string {
 			quitStyle.Render("Press Enter to execute, or ctrl+c to quit."),
 		)
 	case stateExecuting:
-		logs := strings.Join(m.executionInfos, "\n  ")
-		return fmt.Sprintf("\n %s Executing Workflow...\n\n  %s\n", m.spinner.View(), logs)
+		return fmt.Sprintf("\n %s Executing Workflow...\n\n%s", m.spinner.View(), m.executionView())
+	case stateRollingBack:
+		return fmt.Sprintf("\n %s Rolling back...\n\n%s", m.spinner.View(), m.executionView())
 	case stateDone:
-		return successStyle.Render(fmt.Sprintf("\nSuccess!\n%s\n", m.successMsg))
+		return "\n" + m.executionView() + successStyle.Render(fmt.Sprintf("\nSuccess!\n%s\n", m.successMsg))
 	}
 
 	return ""
@@ -251,26 +475,60 @@ func (m model) View() string {
 type errMsg struct{ err error }
 
 type clientsReadyMsg struct {
-	auth   *auth.Authenticator
-	fabric *fabric.Client
-	devops *devops.Client
+	auth           *auth.Authenticator
+	fabric         *fabric.Client
+	devops         *devops.Client
+	github         *github.Client
+	parameters     *parameters.File
+	parametersFile string
+	environment    string
+	profileName    string
+	profile        *config.Profile
+	runStore       *workflow.RunStore
+	pendingRun     *workflow.Run
 }
 
 type workspacesMsg struct{ workspaces []fabric.Workspace }
+type deviceCodeMsg struct{ message string }
 type gitConnectionMsg struct{ details *fabric.GitProviderDetails }
-type executionStepMsg struct{ info string }
+type executionStepMsg struct{ event workflow.Event }
 type executionDoneMsg struct{ msg string }
 
+type executionFailedMsg struct {
+	err    error
+	result workflow.Result
+}
+
+type rollbackDoneMsg struct {
+	err    error
+	result workflow.Result
+}
+
 func initClientsCmd() tea.Msg {
-	a, err := auth.NewAuthenticator()
+	c, err := newClients()
 	if err != nil {
 		return errMsg{err}
 	}
-	return clientsReadyMsg{
-		auth:   a,
-		fabric: fabric.NewClient(a),
-		devops: devops.NewClient(a),
+	msg := clientsReadyMsg{
+		auth:           c.auth,
+		fabric:         c.fabric,
+		devops:         c.devops,
+		github:         c.github,
+		parameters:     c.parameters,
+		parametersFile: c.parametersFile,
+		environment:    c.environment,
+		profileName:    c.profileName,
+		profile:        c.profile,
 	}
+
+	// Runs are only journaled if we have somewhere to keep them.
+	if store, err := workflow.DefaultRunStore(); err == nil {
+		msg.runStore = store
+		if runs, err := store.Unfinished(); err == nil && len(runs) > 0 {
+			msg.pendingRun = runs[0]
+		}
+	}
+	return msg
 }
 
 func (m model) fetchWorkspacesCmd() tea.Msg {
@@ -293,85 +551,65 @@ func (m model) fetchGitConnectionCmd(id string) tea.Cmd {
 	}
 }
 
-func (m model) executeFlowCmd() tea.Msg {
-	ctx := context.Background()
-	gitInfo := m.selectedDevWorkspace.GitProviderDetails
-
-	// 1. Get Base Commit ID
-	baseCommitId, err := m.devopsClient.GetBranchObjectId(ctx, gitInfo.OrganizationName, gitInfo.ProjectName, gitInfo.RepositoryName, gitInfo.BranchName)
-	if err != nil {
-		return errMsg{fmt.Errorf("getting dev branch commit: %w", err)}
-	}
-
-	// Wait, bubble tea cmd cannot yield multiple tea.Msgs directly via channel easily without returning a Cmd per message. Let's just do it sequentially or send msgs using another method.
-	// We'll just do it all here for now and report success at the end, returning errMsg if anything fails.
-
-	// Create Branch
-	err = m.devopsClient.CreateBranch(ctx, gitInfo.OrganizationName, gitInfo.ProjectName, gitInfo.RepositoryName, m.newBranchName, baseCommitId)
-	if err != nil {
-		return errMsg{fmt.Errorf("creating feature branch: %w", err)}
-	}
-
-	// Create Workspace
-	req := fabric.CreateWorkspaceRequest{
-		DisplayName: m.newWorkspaceName,
-		Description: "Feature workspace for " + m.newBranchName + " (Parent: " + m.selectedDevWorkspace.DisplayName + ")",
-		CapacityId:  m.selectedDevWorkspace.CapacityId,
-	}
-	newWs, err := m.fabricClient.CreateWorkspace(ctx, req)
-	if err != nil {
-		return errMsg{fmt.Errorf("creating workspace: %w", err)}
-	}
+// startExecution switches to the execution view and runs the workflow in the background.
+func (m model) startExecution() (tea.Model, tea.Cmd) {
+	m.state = stateExecuting
+	m.executionCh = make(chan tea.Msg)
+	go m.executeFlow(m.executionCh)
+	return m, tea.Batch(m.spinner.Tick, waitForExecution(m.executionCh))
+}
 
-	// Connect to Git
-	newGitInfo := *gitInfo
-	newGitInfo.BranchName = m.newBranchName
-	err = m.fabricClient.ConnectWorkspaceToGit(ctx, newWs.Id, fabric.ConnectToGitRequest{GitProviderDetails: &newGitInfo})
-	if err != nil {
-		return errMsg{fmt.Errorf("connecting git: %w", err)}
+// executeFlow runs (or resumes) the feature workflow, streaming step events and the final
+// outcome to ch.
+func (m model) executeFlow(ch chan<- tea.Msg) {
+	ctx := context.Background()
+	var res workflow.Result
+	var err error
+	if m.resumeRun != nil {
+		res, err = workflow.ResumeFeatureEnvironment(ctx, m.workflowOptions(ch), m.resumeRun)
+	} else {
+		res, err = workflow.CreateFeatureEnvironment(ctx, m.workflowOptions(ch))
 	}
-
-	// Initialize Git Connection
-	err = m.fabricClient.InitializeGitConnection(ctx, newWs.Id)
 	if err != nil {
-		return errMsg{fmt.Errorf("initializing git connection: %w", err)}
+		ch <- executionFailedMsg{err: err, result: res}
+		return
 	}
+	ch <- executionDoneMsg{"Workspace and Branch created and synced successfully!"}
+}
 
-	// Fabric requires a brief moment to initialize the git status after connection
-	time.Sleep(3 * time.Second)
-
-	// Get Git Status to find remoteCommitHash
-	gitStatus, err := m.fabricClient.GetGitStatus(ctx, newWs.Id)
-	if err != nil {
-		return errMsg{fmt.Errorf("getting git status: %w", err)}
-	}
+// rollback removes what a failed run created, streaming step events and the outcome to ch.
+func (m model) rollback(ch chan<- tea.Msg, res workflow.Result) {
+	err := workflow.Rollback(context.Background(), m.workflowOptions(ch), &res)
+	ch <- rollbackDoneMsg{err: err, result: res}
+}
 
-	// Update from Git
-	opId, err := m.fabricClient.UpdateWorkspaceFromGit(ctx, newWs.Id, gitStatus.WorkspaceHead, gitStatus.RemoteCommitHash)
-	if err != nil {
-		return errMsg{fmt.Errorf("updating from git: %w", err)}
-	}
+// rollbackRun removes what a journaled run created, streaming step events and the outcome to ch.
+func (m model) rollbackRun(ch chan<- tea.Msg, run *workflow.Run) {
+	res, err := workflow.RollbackRun(context.Background(), m.workflowOptions(ch), run)
+	ch <- rollbackDoneMsg{err: err, result: res}
+}
 
-	if opId != "" {
-		// Poll for completion
-		for {
-			time.Sleep(2 * time.Second)
-			status, err := m.fabricClient.GetOperationStatus(ctx, opId)
-			if err != nil {
-				return errMsg{fmt.Errorf("checking operation status: %w", err)}
-			}
-			if status.Status == "Succeeded" {
-				break
-			} else if status.Status == "Failed" {
-				return errMsg{fmt.Errorf("git sync failed: [%s] %s", status.Error.ErrorCode, status.Error.Message)}
-			}
-		}
+func (m model) workflowOptions(ch chan<- tea.Msg) workflow.Options {
+	return workflow.Options{
+		Fabric:         m.fabricClient,
+		DevOps:         m.devopsClient,
+		GitHub:         m.githubClient,
+		Parameters:     m.parameters,
+		ParametersFile: m.parametersFile,
+		Environment:    m.environment,
+		Parent:         m.selectedDevWorkspace,
+		BranchName:     m.newBranchName,
+		WorkspaceName:  m.newWorkspaceName,
+		CapacityId:     profileCapacityId(m.profile),
+		Store:          m.runStore,
+		OnEvent: func(ev workflow.Event) {
+			ch <- executionStepMsg{ev}
+		},
 	}
+}
 
-	// Update Connections
-	// err = m.fabricClient.UpdateConnections(ctx, newWs.Id, nil)
-
-	return executionDoneMsg{"Workspace and Branch created and synced successfully!"}
+func (m model) canRollback() bool {
+	return m.executionResult != nil && m.executionResult.NeedsRollback()
 }
 
 // ----- Helps -----
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	commitKey    = key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "commit changes"))
+	toggleKey    = key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "select"))
+	toggleAllKey = key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "select all"))
+	continueKey  = key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "continue"))
+)
+
+type changesMsg struct {
+	workspace fabric.Workspace
+	status    *fabric.GitStatus
+}
+
+type commitDoneMsg struct{ count int }
+
+func newChangeList() list.Model {
+	lst := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	lst.SetShowStatusBar(false)
+	lst.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{toggleKey, toggleAllKey, continueKey, backKey}
+	}
+	return lst
+}
+
+func newCommitInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "Describe your changes"
+	ti.CharLimit = maxCommitComment
+	return ti
+}
+
+func (m model) fetchChangesCmd(ws fabric.Workspace) tea.Cmd {
+	return func() tea.Msg {
+		status, err := m.fabricClient.GetGitStatus(context.Background(), ws.Id)
+		if err != nil {
+			return errMsg{fmt.Errorf("getting git status of %s: %w", ws.DisplayName, err)}
+		}
+		return changesMsg{workspace: ws, status: status}
+	}
+}
+
+// startCommit opens the commit screen for ws.
+func (m model) startCommit(ws fabric.Workspace) (tea.Model, tea.Cmd) {
+	m.commitWorkspace = ws
+	m.commitFrom = m.state
+	m.state = stateLoadingChanges
+	return m, tea.Batch(m.spinner.Tick, m.fetchChangesCmd(ws))
+}
+
+// setChanges fills the commit screen with the pending changes, all selected.
+func (m *model) setChanges(msg changesMsg) {
+	m.commitWorkspace = msg.workspace
+	m.commitStatus = msg.status
+	changes := pendingChanges(msg.status)
+	items := make([]list.Item, len(changes))
+	for i, c := range changes {
+		items[i] = changeItem{change: c, selected: true}
+	}
+	m.changeLst.SetItems(items)
+	m.changeLst.ResetFilter()
+	m.changeLst.Select(0)
+	m.changeLst.Title = fmt.Sprintf("Changes in %s", msg.workspace.DisplayName)
+	if len(changes) == 0 {
+		m.changeLst.Title = fmt.Sprintf("No changes to commit in %s", msg.workspace.DisplayName)
+	}
+	m.commitInput.Reset()
+}
+
+// selectedChanges returns the changes picked on the commit screen.
+func (m model) selectedChanges() []fabric.ItemChange {
+	var changes []fabric.ItemChange
+	for _, it := range m.changeLst.Items() {
+		if c := it.(changeItem); c.selected {
+			changes = append(changes, c.change)
+		}
+	}
+	return changes
+}
+
+// updateChangeSelection handles keys on the commit screen's change list.
+func (m model) updateChangeSelection(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && m.changeLst.FilterState() != list.Filtering {
+		switch {
+		case key.Matches(msg, backKey) && m.changeLst.FilterState() == list.Unfiltered:
+			m.state = m.commitFrom
+			return m, nil
+		case key.Matches(msg, toggleKey):
+			if c, ok := m.changeLst.SelectedItem().(changeItem); ok {
+				c.selected = !c.selected
+				return m, m.changeLst.SetItem(m.changeLst.GlobalIndex(), c)
+			}
+		case key.Matches(msg, toggleAllKey):
+			items := m.changeLst.Items()
+			all := len(m.selectedChanges()) == len(items)
+			for i, it := range items {
+				c := it.(changeItem)
+				c.selected = !all
+				items[i] = c
+			}
+			return m, m.changeLst.SetItems(items)
+		case key.Matches(msg, continueKey):
+			if len(m.selectedChanges()) > 0 {
+				m.state = stateEnterCommitMessage
+				m.commitInput.Focus()
+				return m, textinput.Blink
+			}
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.changeLst, cmd = m.changeLst.Update(msg)
+	return m, cmd
+}
+
+// updateCommitMessage handles keys while the commit message is entered.
+func (m model) updateCommitMessage(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, backKey):
+			m.commitInput.Blur()
+			m.state = stateSelectChanges
+			return m, nil
+		case msg.String() == "enter" && m.commitInput.Value() != "":
+			m.state = stateCommitting
+			return m, tea.Batch(m.spinner.Tick, m.commitCmd())
+		}
+	}
+	var cmd tea.Cmd
+	m.commitInput, cmd = m.commitInput.Update(msg)
+	return m, cmd
+}
+
+func (m model) commitCmd() tea.Cmd {
+	changes := m.selectedChanges()
+	all := len(changes) == len(m.changeLst.Items())
+	ws, status, comment := m.commitWorkspace, m.commitStatus, m.commitInput.Value()
+	return func() tea.Msg {
+		if err := commitToGit(context.Background(), m.fabricClient, ws.Id, status, changes, comment, all); err != nil {
+			return errMsg{err}
+		}
+		return commitDoneMsg{count: len(changes)}
+	}
+}
+
+func (m model) commitMessageView() string {
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		fmt.Sprintf("\n  Commit %d change(s) in %s. Enter a commit message:", len(m.selectedChanges()), m.commitWorkspace.DisplayName),
+		"  "+m.commitInput.View(),
+		quitStyle.Render("Press Enter to commit, esc to go back, or ctrl+c to quit."),
+	)
+}
+
+// changeItem is a list entry on the commit screen.
+type changeItem struct {
+	change   fabric.ItemChange
+	selected bool
+}
+
+func (i changeItem) Title() string {
+	box := "[ ] "
+	if i.selected {
+		box = "[x] "
+	}
+	return box + i.change.ItemMetadata.DisplayName
+}
+
+func (i changeItem) Description() string {
+	d := i.change.WorkspaceChange + " · " + i.change.ItemMetadata.ItemType
+	if i.change.ConflictType == fabric.ConflictTypeConflict {
+		d += " · conflict"
+	}
+	return d
+}
+
+func (i changeItem) FilterValue() string {
+	return i.change.ItemMetadata.DisplayName + " " + i.change.ItemMetadata.ItemType
+}
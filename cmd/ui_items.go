@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pkg/browser"
+)
+
+var (
+	detailStyle      = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("241")).Padding(0, 1)
+	detailLabelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Width(14)
+	typeHeaderStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("170")).PaddingLeft(2)
+)
+
+// portalURL is the Fabric portal that "open in browser" links to.
+const portalURL = "https://app.fabric.microsoft.com"
+
+// portalPaths maps item types to their path segment in portal URLs.
+var portalPaths = map[string]string{
+	fabric.ItemTypeLakehouse:          "lakehouses",
+	fabric.ItemTypeNotebook:           "synapsenotebooks",
+	fabric.ItemTypeDataPipeline:       "pipelines",
+	fabric.ItemTypeSparkJobDefinition: "sparkjobdefinitions",
+	fabric.ItemTypeSemanticModel:      "datasets",
+	fabric.ItemTypeReport:             "reports",
+}
+
+var (
+	browseKey = key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "browse items"))
+	createKey = key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "create feature"))
+	openKey   = key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "open in browser"))
+	copyKey   = key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy id"))
+	exportKey = key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "export definition"))
+	backKey   = key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back"))
+)
+
+func init() {
+	// The browser package prints to stdout, which would corrupt the TUI.
+	browser.Stdout = io.Discard
+	browser.Stderr = io.Discard
+}
+
+// errGitStatusUnknown marks a git status that came back without the workspace or remote head.
+var errGitStatusUnknown = errors.New("git status is not available")
+
+type itemsMsg struct {
+	items  []fabric.Item
+	status *fabric.GitStatus
+	// statusErr is set if the git status could not be read, e.g. for a workspace without git.
+	statusErr error
+}
+
+type itemActionMsg struct {
+	msg string
+	err error
+}
+
+func newItemList() list.Model {
+	lst := list.New([]list.Item{}, itemDelegate{list.NewDefaultDelegate()}, 0, 0)
+	lst.SetShowStatusBar(false)
+	lst.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{openKey, copyKey, exportKey, commitKey, backKey}
+	}
+	return lst
+}
+
+func (m model) fetchItemsCmd(ws fabric.Workspace) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		items, err := m.fabricClient.ListItems(ctx, ws.Id, "")
+		if err != nil {
+			return errMsg{fmt.Errorf("listing items in %s: %w", ws.DisplayName, err)}
+		}
+		status, err := m.fabricClient.GetGitStatus(ctx, ws.Id)
+		if err == nil && status.WorkspaceHead == "" && status.RemoteCommitHash == "" {
+			// Without either head the status says nothing about the items.
+			err = errGitStatusUnknown
+		}
+		return itemsMsg{items: items, status: status, statusErr: err}
+	}
+}
+
+// setItems fills the item browser, grouping items by type under a header per type.
+func (m *model) setItems(msg itemsMsg) {
+	slices.SortFunc(msg.items, func(a, b fabric.Item) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(strings.ToLower(a.DisplayName), strings.ToLower(b.DisplayName)))
+	})
+	changes := map[string]*fabric.ItemChange{}
+	if msg.status != nil {
+		for i, c := range msg.status.Changes {
+			if id := c.ItemMetadata.ItemIdentifier.ObjectId; id != "" {
+				changes[id] = &msg.status.Changes[i]
+			}
+		}
+	}
+
+	var items []list.Item
+	for i, it := range msg.items {
+		if i == 0 || it.Type != msg.items[i-1].Type {
+			n := 0
+			for _, o := range msg.items[i:] {
+				if o.Type != it.Type {
+					break
+				}
+				n++
+			}
+			items = append(items, typeHeader{itemType: it.Type, count: n})
+		}
+		items = append(items, fabricItem{item: it, change: changes[it.Id], gitErr: msg.statusErr})
+	}
+	m.itemLst.SetItems(items)
+	m.itemLst.ResetFilter()
+	m.itemLst.Select(0)
+	skipItemHeader(&m.itemLst, true)
+	m.itemLst.Title = fmt.Sprintf("Items in %s", m.browseWorkspace.DisplayName)
+	m.itemStatus = ""
+}
+
+// skipItemHeader moves the cursor off a group header, in the direction it was moving if
+// possible.
+func skipItemHeader(lst *list.Model, down bool) {
+	visible := lst.VisibleItems()
+	i := lst.Index()
+	for _, dir := range []int{1, -1} {
+		if !down {
+			dir = -dir
+		}
+		for j := i; j >= 0 && j < len(visible); j += dir {
+			if _, ok := visible[j].(typeHeader); !ok {
+				lst.Select(j)
+				return
+			}
+		}
+	}
+}
+
+// updateItemBrowser handles keys in the item browser.
+func (m model) updateItemBrowser(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && m.itemLst.FilterState() != list.Filtering {
+		selected, ok := m.itemLst.SelectedItem().(fabricItem)
+		switch {
+		case key.Matches(msg, backKey) && m.itemLst.FilterState() == list.Unfiltered:
+			m.state = stateSelectWorkspace
+			return m, nil
+		case key.Matches(msg, openKey) && ok:
+			return m, openItemCmd(selected.item)
+		case key.Matches(msg, copyKey) && ok:
+			return m, copyItemIdCmd(selected.item)
+		case key.Matches(msg, commitKey):
+			return m.startCommit(m.browseWorkspace)
+		case key.Matches(msg, exportKey) && ok:
+			m.itemStatus = "Exporting " + selected.item.DisplayName + "..."
+			return m, m.exportItemCmd(selected.item)
+		}
+	}
+	prev := m.itemLst.Index()
+	var cmd tea.Cmd
+	m.itemLst, cmd = m.itemLst.Update(msg)
+	skipItemHeader(&m.itemLst, m.itemLst.Index() >= prev)
+	return m, cmd
+}
+
+// itemBrowserView renders the item list next to the selected item's details.
+func (m model) itemBrowserView() string {
+	view := lipgloss.JoinHorizontal(lipgloss.Top, m.itemLst.View(), m.itemDetailView())
+	if m.itemStatus != "" {
+		view += "\n" + quitStyle.Render(m.itemStatus)
+	}
+	return "\n" + view
+}
+
+func (m model) itemDetailView() string {
+	selected, ok := m.itemLst.SelectedItem().(fabricItem)
+	if !ok {
+		return ""
+	}
+	it := selected.item
+	description := it.Description
+	if description == "" {
+		description = "-"
+	}
+	rows := [][2]string{
+		{"Name", it.DisplayName},
+		{"Type", it.Type},
+		{"Id", it.Id},
+		{"Description", description},
+		{"Workspace", m.browseWorkspace.DisplayName},
+		{"Git", selected.gitState()},
+	}
+	var b strings.Builder
+	for i, r := range rows {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(detailLabelStyle.Render(r[0]) + r[1])
+	}
+	return detailStyle.Width(m.itemDetailWidth()).Render(b.String())
+}
+
+// resizeItemBrowser splits the window between the item list and the detail panel.
+func (m *model) resizeItemBrowser(width, height int) {
+	m.itemLst.SetSize(width/2, height)
+}
+
+func (m model) itemDetailWidth() int {
+	return max(m.itemLst.Width()-4, 30)
+}
+
+func openItemCmd(it fabric.Item) tea.Cmd {
+	return func() tea.Msg {
+		url := itemPortalURL(it)
+		if err := browser.OpenURL(url); err != nil {
+			return itemActionMsg{err: fmt.Errorf("opening %s: %w", url, err)}
+		}
+		return itemActionMsg{msg: "Opened " + url}
+	}
+}
+
+// itemPortalURL links to the item in the Fabric portal, or to its workspace for item types
+// without a known page.
+func itemPortalURL(it fabric.Item) string {
+	url := portalURL + "/groups/" + it.WorkspaceId
+	if p, ok := portalPaths[it.Type]; ok {
+		url += "/" + p + "/" + it.Id
+	}
+	return url
+}
+
+func copyItemIdCmd(it fabric.Item) tea.Cmd {
+	return func() tea.Msg {
+		if err := clipboard.WriteAll(it.Id); err != nil {
+			return itemActionMsg{err: fmt.Errorf("copying %s: %w", it.Id, err)}
+		}
+		return itemActionMsg{msg: "Copied " + it.Id}
+	}
+}
+
+func (m model) exportItemCmd(it fabric.Item) tea.Cmd {
+	return func() tea.Msg {
+		dir, err := exportDefinition(context.Background(), m.fabricClient, it, ".")
+		if err != nil {
+			return itemActionMsg{err: fmt.Errorf("exporting %s: %w", it.DisplayName, err)}
+		}
+		return itemActionMsg{msg: "Exported " + it.DisplayName + " to " + dir}
+	}
+}
+
+// exportDefinition writes the item's definition parts to "<name>.<type>" under parent, the
+// layout Fabric uses in git, and returns that directory.
+func exportDefinition(ctx context.Context, api fabric.API, it fabric.Item, parent string) (string, error) {
+	def, err := api.GetItemDefinition(ctx, it.WorkspaceId, it.Id, "")
+	if err != nil {
+		return "", err
+	}
+	files, err := def.Files()
+	if err != nil {
+		return "", err
+	}
+	name := strings.NewReplacer("/", "_", `\`, "_").Replace(it.DisplayName)
+	dir := filepath.Join(parent, name+"."+it.Type)
+	for path, content := range files {
+		if !filepath.IsLocal(path) {
+			return "", fmt.Errorf("definition part %q is outside the item directory", path)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(target, content, 0o644); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// typeHeader is the row above the items of one type in the item browser. The cursor skips it.
+type typeHeader struct {
+	itemType string
+	count    int
+}
+
+// FilterValue is empty so headers are hidden while filtering.
+func (h typeHeader) FilterValue() string { return "" }
+
+// itemDelegate renders type headers and items in the item browser.
+type itemDelegate struct {
+	list.DefaultDelegate
+}
+
+func (d itemDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if h, ok := item.(typeHeader); ok {
+		// Fill the delegate's height so the rows below stay aligned.
+		fmt.Fprint(w, typeHeaderStyle.Render(fmt.Sprintf("%s (%d)", h.itemType, h.count))+strings.Repeat("\n", d.Height()-1))
+		return
+	}
+	d.DefaultDelegate.Render(w, m, index, item)
+}
+
+// fabricItem is a list entry in the item browser.
+type fabricItem struct {
+	item   fabric.Item
+	change *fabric.ItemChange
+	gitErr error
+}
+
+func (i fabricItem) Title() string { return i.item.DisplayName }
+
+func (i fabricItem) Description() string {
+	if i.change != nil {
+		return i.item.Type + " · " + i.gitState()
+	}
+	return i.item.Type
+}
+
+// FilterValue lets the filter match an item's type as well as its name.
+func (i fabricItem) FilterValue() string { return i.item.DisplayName + " " + i.item.Type }
+
+func (i fabricItem) gitState() string {
+	switch {
+	case i.gitErr != nil:
+		if fabric.IsErrorCode(i.gitErr, fabric.ErrorCodeWorkspaceNotConnectedToGit) {
+			return "not connected"
+		}
+		return "unknown"
+	case i.change == nil:
+		return "in sync"
+	case i.change.ConflictType == fabric.ConflictTypeConflict:
+		return "conflict"
+	case i.change.WorkspaceChange != "":
+		return strings.ToLower(i.change.WorkspaceChange) + " in workspace (uncommitted)"
+	default:
+		return strings.ToLower(i.change.RemoteChange) + " in git (update pending)"
+	}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amaliebjorgen/fabricant/pkg/workflow"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	stepDoneStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	stepFailedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	stepDetailStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+)
+
+// executionStep tracks the state of a single workflow step for the execution view.
+type executionStep struct {
+	step     workflow.Step
+	message  string
+	detail   string
+	percent  int
+	started  time.Time
+	finished time.Time
+	failed   bool
+	skipped  bool
+}
+
+// applyExecutionEvent folds a workflow event into the list of steps shown while executing.
+func (m *model) applyExecutionEvent(ev workflow.Event) {
+	idx := -1
+	for i := range m.executionSteps {
+		if m.executionSteps[i].step == ev.Step {
+			idx = i
+		}
+	}
+	if ev.Kind == workflow.EventStepStarted || idx == -1 {
+		m.executionSteps = append(m.executionSteps, executionStep{
+			step:    ev.Step,
+			message: ev.Message,
+			percent: -1,
+			started: ev.Time,
+			skipped: ev.Skipped,
+		})
+		idx = len(m.executionSteps) - 1
+	}
+
+	s := &m.executionSteps[idx]
+	switch ev.Kind {
+	case workflow.EventStepProgress:
+		s.detail = ev.Message
+		s.percent = ev.PercentComplete
+	case workflow.EventStepCompleted:
+		s.finished = ev.Time
+	case workflow.EventStepFailed:
+		s.finished = ev.Time
+		s.failed = true
+	}
+}
+
+// executionView renders the steps reached so far with check marks and timings.
+func (m model) executionView() string {
+	var b strings.Builder
+	for _, s := range m.executionSteps {
+		switch {
+		case s.failed:
+			fmt.Fprintf(&b, "  %s %s %s\n", stepFailedStyle.Render("✗"), s.message, stepDetailStyle.Render(formatElapsed(s.finished.Sub(s.started))))
+		case s.skipped:
+			fmt.Fprintf(&b, "  %s %s %s\n", stepDoneStyle.Render("✓"), s.message, stepDetailStyle.Render("(already done)"))
+		case !s.finished.IsZero():
+			fmt.Fprintf(&b, "  %s %s %s\n", stepDoneStyle.Render("✓"), s.message, stepDetailStyle.Render(formatElapsed(s.finished.Sub(s.started))))
+		default:
+			detail := formatElapsed(time.Since(s.started))
+			if s.percent >= 0 {
+				detail = fmt.Sprintf("%d%% · %s", s.percent, detail)
+			}
+			if s.detail != "" {
+				detail = s.detail + " · " + detail
+			}
+			fmt.Fprintf(&b, "  %s %s %s\n", m.spinner.View(), s.message, stepDetailStyle.Render(detail))
+		}
+	}
+	return b.String()
+}
+
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("(%.1fs)", d.Seconds())
+	}
+	return fmt.Sprintf("(%s)", d.Round(time.Second))
+}
+
+// waitForExecution returns a command that delivers the next message from a running workflow.
+func waitForExecution(ch <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-ch
+	}
+}
@@ -2,32 +2,53 @@ package auth
 
 import (
 	"context"
-	"fmt"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 )
 
-// Authenticator provides tokens for accessing Azure & Fabric resources.
+// TokenSource provides access tokens for API scopes. It is implemented by *Authenticator and
+// lets the API clients be used with other token providers, such as StaticToken in tests.
+type TokenSource interface {
+	GetToken(ctx context.Context, scopes []string) (azcore.AccessToken, error)
+}
+
+// StaticToken returns a TokenSource that always returns token, e.g. for fake servers.
+func StaticToken(token string) TokenSource {
+	return staticToken(token)
+}
+
+type staticToken string
+
+func (t staticToken) GetToken(ctx context.Context, scopes []string) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: string(t), ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// Authenticator provides tokens for accessing Azure & Fabric resources. Tokens are cached in
+// memory per scope and refreshed before they expire.
 type Authenticator struct {
-	cred *azidentity.AzureCLICredential
+	cache *tokenCache
 }
 
 // NewAuthenticator creates a new authenticator using the az cli context.
 func NewAuthenticator() (*Authenticator, error) {
-	cred, err := azidentity.NewAzureCLICredential(nil)
+	return NewAuthenticatorWithOptions(Options{Credential: CredentialAzureCLI})
+}
+
+// NewAuthenticatorWithOptions creates an authenticator for the credential selected in opts.
+func NewAuthenticatorWithOptions(opts Options) (*Authenticator, error) {
+	cred, err := newCredential(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Azure CLI credential: %w", err)
+		return nil, err
 	}
 	return &Authenticator{
-		cred: cred,
+		cache: newTokenCache(cred),
 	}, nil
 }
 
 // GetToken returns an OAuth token for the requested scopes.
 func (a *Authenticator) GetToken(ctx context.Context, scopes []string) (azcore.AccessToken, error) {
-	return a.cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: scopes})
+	return a.cache.getToken(ctx, scopes)
 }
 
 // DevOpsScope is the target scope for Azure DevOps REST APIs.
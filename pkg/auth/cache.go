@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const (
+	// refreshWindow is how long before expiry a cached token is refreshed in the background.
+	refreshWindow = 5 * time.Minute
+	// expirySkew treats tokens as expired slightly early to cover clock skew and request time.
+	expirySkew = 30 * time.Second
+	// fetchTimeout bounds a credential call. It is generous because interactive credentials wait
+	// for the user to sign in.
+	fetchTimeout = 5 * time.Minute
+)
+
+// tokenCache caches tokens per scope set, refreshing them ahead of expiry and sharing one
+// in-flight credential call among concurrent callers.
+type tokenCache struct {
+	cred azcore.TokenCredential
+
+	mu       sync.Mutex
+	tokens   map[string]azcore.AccessToken
+	inflight map[string]*tokenFetch
+}
+
+// tokenFetch is a credential call that concurrent callers for the same scopes wait on.
+type tokenFetch struct {
+	done  chan struct{}
+	token azcore.AccessToken
+	err   error
+}
+
+func newTokenCache(cred azcore.TokenCredential) *tokenCache {
+	return &tokenCache{
+		cred:     cred,
+		tokens:   map[string]azcore.AccessToken{},
+		inflight: map[string]*tokenFetch{},
+	}
+}
+
+func (c *tokenCache) getToken(ctx context.Context, scopes []string) (azcore.AccessToken, error) {
+	key := strings.Join(scopes, " ")
+	now := time.Now()
+
+	c.mu.Lock()
+	tok, ok := c.tokens[key]
+	if ok && now.Before(tok.ExpiresOn.Add(-expirySkew)) {
+		if now.After(refreshAt(tok)) {
+			// Still valid: hand it out and renew it without making the caller wait.
+			c.startFetch(key, scopes)
+		}
+		c.mu.Unlock()
+		return tok, nil
+	}
+	f := c.startFetch(key, scopes)
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.token, f.err
+	case <-ctx.Done():
+		return azcore.AccessToken{}, ctx.Err()
+	}
+}
+
+// startFetch returns the in-flight fetch for key, starting one if needed. c.mu must be held.
+func (c *tokenCache) startFetch(key string, scopes []string) *tokenFetch {
+	if f, ok := c.inflight[key]; ok {
+		return f
+	}
+	f := &tokenFetch{done: make(chan struct{})}
+	c.inflight[key] = f
+
+	go func() {
+		// The fetch is shared, so it must not be cancelled by whichever caller started it.
+		ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+		defer cancel()
+		f.token, f.err = c.cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: scopes})
+
+		c.mu.Lock()
+		if f.err == nil {
+			c.tokens[key] = f.token
+		}
+		delete(c.inflight, key)
+		c.mu.Unlock()
+		close(f.done)
+	}()
+	return f
+}
+
+// refreshAt is when a token should be renewed: the service's hint if given, otherwise
+// refreshWindow before it expires.
+func refreshAt(tok azcore.AccessToken) time.Time {
+	if !tok.RefreshOn.IsZero() {
+		return tok.RefreshOn
+	}
+	return tok.ExpiresOn.Add(-refreshWindow)
+}
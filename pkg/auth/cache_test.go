@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// fakeCredential hands out numbered tokens that expire after lifetime, counting calls.
+type fakeCredential struct {
+	lifetime time.Duration
+	// refreshIn, if set, is the refresh hint on issued tokens.
+	refreshIn time.Duration
+	// fail makes the next call fail.
+	fail bool
+	// release, if set, holds every call until it is closed.
+	release chan struct{}
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *fakeCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	if c.release != nil {
+		<-c.release
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.fail {
+		c.fail = false
+		return azcore.AccessToken{}, errors.New("sign-in failed")
+	}
+	tok := azcore.AccessToken{
+		Token:     fmt.Sprintf("%s#%d", opts.Scopes[0], c.calls),
+		ExpiresOn: time.Now().Add(c.lifetime),
+	}
+	if c.refreshIn != 0 {
+		tok.RefreshOn = time.Now().Add(c.refreshIn)
+	}
+	return tok, nil
+}
+
+func (c *fakeCredential) callCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func mustToken(t *testing.T, c *tokenCache, scope string) string {
+	t.Helper()
+	tok, err := c.getToken(context.Background(), []string{scope})
+	if err != nil {
+		t.Fatalf("getToken(%s): %v", scope, err)
+	}
+	return tok.Token
+}
+
+func TestTokenCachePerScope(t *testing.T) {
+	cred := &fakeCredential{lifetime: time.Hour}
+	c := newTokenCache(cred)
+
+	if got := mustToken(t, c, "fabric"); got != "fabric#1" {
+		t.Errorf("first fabric token = %s, want fabric#1", got)
+	}
+	if got := mustToken(t, c, "fabric"); got != "fabric#1" {
+		t.Errorf("second fabric token = %s, want the cached fabric#1", got)
+	}
+	if got := mustToken(t, c, "devops"); got != "devops#2" {
+		t.Errorf("devops token = %s, want devops#2", got)
+	}
+	if n := cred.callCount(); n != 2 {
+		t.Errorf("credential called %d times, want 2", n)
+	}
+}
+
+func TestTokenCacheRefetchesExpiredToken(t *testing.T) {
+	// Tokens inside the expiry skew count as expired.
+	cred := &fakeCredential{lifetime: expirySkew / 2}
+	c := newTokenCache(cred)
+
+	mustToken(t, c, "fabric")
+	if got := mustToken(t, c, "fabric"); got != "fabric#2" {
+		t.Errorf("token = %s, want a new fabric#2", got)
+	}
+}
+
+func TestTokenCacheRefreshesInBackground(t *testing.T) {
+	cred := &fakeCredential{lifetime: time.Hour, refreshIn: -time.Minute}
+	c := newTokenCache(cred)
+
+	mustToken(t, c, "fabric")
+	// Past its refresh time the cached token is still handed out while a new one is fetched.
+	if got := mustToken(t, c, "fabric"); got != "fabric#1" {
+		t.Errorf("token = %s, want the cached fabric#1", got)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for mustToken(t, c, "fabric") == "fabric#1" {
+		if time.Now().After(deadline) {
+			t.Fatal("token was not refreshed in the background")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTokenCacheSharesInflightFetch(t *testing.T) {
+	cred := &fakeCredential{lifetime: time.Hour, release: make(chan struct{})}
+	c := newTokenCache(cred)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	tokens := make([]string, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tok, err := c.getToken(context.Background(), []string{"fabric"})
+			if err != nil {
+				t.Errorf("getToken: %v", err)
+			}
+			tokens[i] = tok.Token
+		}()
+	}
+	// Let the callers pile up on the fetch before it completes.
+	time.Sleep(20 * time.Millisecond)
+	close(cred.release)
+	wg.Wait()
+
+	if n := cred.callCount(); n != 1 {
+		t.Errorf("credential called %d times, want 1", n)
+	}
+	for i, tok := range tokens {
+		if tok != "fabric#1" {
+			t.Errorf("caller %d got %s, want fabric#1", i, tok)
+		}
+	}
+}
+
+func TestTokenCacheDoesNotCacheErrors(t *testing.T) {
+	cred := &fakeCredential{lifetime: time.Hour, fail: true}
+	c := newTokenCache(cred)
+
+	if _, err := c.getToken(context.Background(), []string{"fabric"}); err == nil {
+		t.Fatal("getToken succeeded, want the credential error")
+	}
+	if got := mustToken(t, c, "fabric"); got != "fabric#2" {
+		t.Errorf("token = %s, want fabric#2 from a new call", got)
+	}
+}
+
+func TestTokenCacheCallerCancellation(t *testing.T) {
+	cred := &fakeCredential{lifetime: time.Hour, release: make(chan struct{})}
+	c := newTokenCache(cred)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.getToken(ctx, []string{"fabric"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("getToken error = %v, want context.Canceled", err)
+	}
+	// The shared fetch carries on for other callers.
+	close(cred.release)
+	if got := mustToken(t, c, "fabric"); got != "fabric#1" {
+		t.Errorf("token = %s, want fabric#1", got)
+	}
+}
@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+)
+
+// CloudNames lists the cloud names accepted by ParseCloud.
+var CloudNames = []string{"public", "usgov", "china"}
+
+// ParseCloud returns the Microsoft Entra configuration for a cloud name: "public" (the
+// default), "usgov" or "china". An https:// URL is used as a custom authority host.
+func ParseCloud(name string) (cloud.Configuration, error) {
+	switch strings.ToLower(name) {
+	case "", "public", "azurepublic", "azurecloud":
+		return cloud.AzurePublic, nil
+	case "usgov", "azuregovernment", "azureusgovernment":
+		return cloud.AzureGovernment, nil
+	case "china", "azurechina", "azurechinacloud":
+		return cloud.AzureChina, nil
+	}
+	if strings.HasPrefix(name, "https://") {
+		return cloud.Configuration{ActiveDirectoryAuthorityHost: name}, nil
+	}
+	return cloud.Configuration{}, fmt.Errorf("unknown cloud %q (expected one of %s, or an authority URL)", name, strings.Join(CloudNames, ", "))
+}
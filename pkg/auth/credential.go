@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// CredentialType selects how the Authenticator obtains tokens.
+type CredentialType string
+
+const (
+	// CredentialChain tries the non-interactive credentials that are configured, in the order
+	// service principal, workload identity, Azure CLI, managed identity.
+	CredentialChain              CredentialType = "chain"
+	CredentialAzureCLI           CredentialType = "azurecli"
+	CredentialClientSecret       CredentialType = "clientsecret"
+	CredentialClientCertificate  CredentialType = "clientcertificate"
+	CredentialWorkloadIdentity   CredentialType = "workloadidentity"
+	CredentialManagedIdentity    CredentialType = "managedidentity"
+	CredentialDeviceCode         CredentialType = "devicecode"
+	CredentialInteractiveBrowser CredentialType = "browser"
+)
+
+// CredentialTypes lists the accepted CredentialType values.
+var CredentialTypes = []CredentialType{
+	CredentialChain, CredentialAzureCLI, CredentialClientSecret, CredentialClientCertificate,
+	CredentialWorkloadIdentity, CredentialManagedIdentity, CredentialDeviceCode, CredentialInteractiveBrowser,
+}
+
+// ParseCredentialType validates a credential type name.
+func ParseCredentialType(s string) (CredentialType, error) {
+	if s == "" {
+		return CredentialChain, nil
+	}
+	for _, t := range CredentialTypes {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown credential type %q", s)
+}
+
+// Options configures NewAuthenticatorWithOptions.
+type Options struct {
+	// Credential defaults to CredentialChain.
+	Credential CredentialType
+
+	TenantId string
+	// ClientId is the application id of a service principal, or of a user-assigned managed
+	// identity, or a public client id for the interactive credentials.
+	ClientId            string
+	ClientSecret        string
+	CertificatePath     string
+	CertificatePassword string
+	// FederatedTokenFile holds the federated token for workload identity.
+	FederatedTokenFile string
+
+	// Cloud selects the Microsoft Entra authority, see ParseCloud. The zero value is the public
+	// cloud. The Azure CLI credential uses the CLI's own cloud setting (az cloud set).
+	Cloud cloud.Configuration
+
+	// DeviceCodePrompt shows the device code sign-in instructions. Defaults to
+	// DeviceCodePromptToStderr.
+	DeviceCodePrompt func(message string)
+}
+
+// OptionsFromEnv reads the standard AZURE_* environment variables used by Azure SDKs.
+func OptionsFromEnv() Options {
+	return Options{
+		TenantId:            os.Getenv("AZURE_TENANT_ID"),
+		ClientId:            os.Getenv("AZURE_CLIENT_ID"),
+		ClientSecret:        os.Getenv("AZURE_CLIENT_SECRET"),
+		CertificatePath:     os.Getenv("AZURE_CLIENT_CERTIFICATE_PATH"),
+		CertificatePassword: os.Getenv("AZURE_CLIENT_CERTIFICATE_PASSWORD"),
+		FederatedTokenFile:  os.Getenv("AZURE_FEDERATED_TOKEN_FILE"),
+	}
+}
+
+// DeviceCodePromptToStderr writes device code sign-in instructions to stderr.
+func DeviceCodePromptToStderr(message string) {
+	fmt.Fprintln(os.Stderr, message)
+}
+
+func newCredential(opts Options) (azcore.TokenCredential, error) {
+	clientOpts := azcore.ClientOptions{Cloud: opts.Cloud}
+	switch opts.Credential {
+	case "", CredentialChain:
+		return newChainCredential(opts)
+	case CredentialAzureCLI:
+		cred, err := azidentity.NewAzureCLICredential(&azidentity.AzureCLICredentialOptions{TenantID: opts.TenantId})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure CLI credential: %w", err)
+		}
+		return cred, nil
+	case CredentialClientSecret:
+		if opts.TenantId == "" || opts.ClientId == "" || opts.ClientSecret == "" {
+			return nil, fmt.Errorf("client secret credential requires a tenant id, client id and client secret")
+		}
+		cred, err := azidentity.NewClientSecretCredential(opts.TenantId, opts.ClientId, opts.ClientSecret, &azidentity.ClientSecretCredentialOptions{ClientOptions: clientOpts})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client secret credential: %w", err)
+		}
+		return cred, nil
+	case CredentialClientCertificate:
+		if opts.TenantId == "" || opts.ClientId == "" || opts.CertificatePath == "" {
+			return nil, fmt.Errorf("client certificate credential requires a tenant id, client id and certificate path")
+		}
+		data, err := os.ReadFile(opts.CertificatePath)
+		if err != nil {
+			return nil, fmt.Errorf("reading client certificate: %w", err)
+		}
+		var password []byte
+		if opts.CertificatePassword != "" {
+			password = []byte(opts.CertificatePassword)
+		}
+		certs, key, err := azidentity.ParseCertificates(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("parsing client certificate: %w", err)
+		}
+		cred, err := azidentity.NewClientCertificateCredential(opts.TenantId, opts.ClientId, certs, key, &azidentity.ClientCertificateCredentialOptions{ClientOptions: clientOpts})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client certificate credential: %w", err)
+		}
+		return cred, nil
+	case CredentialWorkloadIdentity:
+		cred, err := azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			ClientOptions: clientOpts,
+			TenantID:      opts.TenantId,
+			ClientID:      opts.ClientId,
+			TokenFilePath: opts.FederatedTokenFile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workload identity credential: %w", err)
+		}
+		return cred, nil
+	case CredentialManagedIdentity:
+		miOpts := &azidentity.ManagedIdentityCredentialOptions{ClientOptions: clientOpts}
+		if opts.ClientId != "" {
+			miOpts.ID = azidentity.ClientID(opts.ClientId)
+		}
+		cred, err := azidentity.NewManagedIdentityCredential(miOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create managed identity credential: %w", err)
+		}
+		return cred, nil
+	case CredentialDeviceCode:
+		prompt := opts.DeviceCodePrompt
+		if prompt == nil {
+			prompt = DeviceCodePromptToStderr
+		}
+		cred, err := azidentity.NewDeviceCodeCredential(&azidentity.DeviceCodeCredentialOptions{
+			ClientOptions: clientOpts,
+			TenantID:      opts.TenantId,
+			ClientID:      opts.ClientId,
+			UserPrompt: func(ctx context.Context, msg azidentity.DeviceCodeMessage) error {
+				prompt(msg.Message)
+				return nil
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create device code credential: %w", err)
+		}
+		return cred, nil
+	case CredentialInteractiveBrowser:
+		cred, err := azidentity.NewInteractiveBrowserCredential(&azidentity.InteractiveBrowserCredentialOptions{
+			ClientOptions: clientOpts,
+			TenantID:      opts.TenantId,
+			ClientID:      opts.ClientId,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create interactive browser credential: %w", err)
+		}
+		return cred, nil
+	}
+	return nil, fmt.Errorf("unknown credential type %q", opts.Credential)
+}
+
+// newChainCredential chains the non-interactive credentials for which opts has enough
+// configuration. Managed identity comes after the Azure CLI because probing for it on a
+// developer machine costs a timeout on every run.
+func newChainCredential(opts Options) (azcore.TokenCredential, error) {
+	var types []CredentialType
+	switch {
+	case opts.ClientSecret != "":
+		types = append(types, CredentialClientSecret)
+	case opts.CertificatePath != "":
+		types = append(types, CredentialClientCertificate)
+	}
+	if opts.FederatedTokenFile != "" {
+		types = append(types, CredentialWorkloadIdentity)
+	}
+	types = append(types, CredentialAzureCLI, CredentialManagedIdentity)
+
+	var sources []azcore.TokenCredential
+	for _, t := range types {
+		o := opts
+		o.Credential = t
+		cred, err := newCredential(o)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, cred)
+	}
+
+	cred, err := azidentity.NewChainedTokenCredential(sources, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credential chain: %w", err)
+	}
+	return cred, nil
+}
@@ -0,0 +1,119 @@
+// Package config reads and writes fabricant's user configuration, which holds named profiles
+// binding a tenant, credential and defaults together.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Profile binds the settings used when working against one tenant.
+type Profile struct {
+	TenantId string `json:"tenantId,omitempty"`
+	// Credential is an auth.CredentialType name.
+	Credential string `json:"credential,omitempty"`
+	ClientId   string `json:"clientId,omitempty"`
+	// DevOpsOrganization is the Azure DevOps organization workspaces are expected to use.
+	DevOpsOrganization string `json:"devopsOrganization,omitempty"`
+	// DevOpsURL is the Azure DevOps collection URL template, see devops.WithBaseURL.
+	DevOpsURL string `json:"devopsUrl,omitempty"`
+	// Capacity is the default capacity id for new feature workspaces.
+	Capacity string `json:"capacity,omitempty"`
+	// ParameterFile is a parameter.yml applied to new feature workspaces for Environment.
+	ParameterFile string `json:"parameterFile,omitempty"`
+	Environment   string `json:"environment,omitempty"`
+
+	// Cloud is the Microsoft Entra cloud, see auth.ParseCloud.
+	Cloud string `json:"cloud,omitempty"`
+	// FabricURL is the Fabric REST API endpoint, see fabric.WithBaseURL.
+	FabricURL string `json:"fabricUrl,omitempty"`
+	// FabricScope and DevOpsScope override the OAuth scopes tokens are requested for.
+	FabricScope string `json:"fabricScope,omitempty"`
+	DevOpsScope string `json:"devopsScope,omitempty"`
+}
+
+// Config is the content of the config file.
+type Config struct {
+	CurrentProfile string              `json:"currentProfile,omitempty"`
+	Profiles       map[string]*Profile `json:"profiles,omitempty"`
+}
+
+// DefaultPath returns the config file location, e.g. ~/.config/fabricant/config.json.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating config directory: %w", err)
+	}
+	return filepath.Join(dir, "fabricant", "config.json"), nil
+}
+
+// Load reads the config at path. A missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the config to path, creating its directory if needed.
+func (c *Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
+
+// Profile returns the named profile.
+func (c *Config) Profile(name string) (*Profile, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found", name)
+	}
+	return p, nil
+}
+
+// SetProfile adds or replaces a profile.
+func (c *Config) SetProfile(name string, p *Profile) {
+	if c.Profiles == nil {
+		c.Profiles = map[string]*Profile{}
+	}
+	c.Profiles[name] = p
+}
+
+// DeleteProfile removes a profile, clearing CurrentProfile if it pointed to it.
+func (c *Config) DeleteProfile(name string) error {
+	if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	delete(c.Profiles, name)
+	if c.CurrentProfile == name {
+		c.CurrentProfile = ""
+	}
+	return nil
+}
+
+// ProfileNames returns the profile names in sorted order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
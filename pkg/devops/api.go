@@ -0,0 +1,17 @@
+package devops
+
+import (
+	"context"
+	"iter"
+)
+
+// API is the set of Azure DevOps operations implemented by *Client, for substituting fakes.
+type API interface {
+	Refs(ctx context.Context, org, project, repo, filter string) iter.Seq2[GitRef, error]
+	GetBranchObjectId(ctx context.Context, org, project, repo, branchName string) (string, error)
+	CreateBranch(ctx context.Context, org, project, repo, newBranchName, baseObjectId string) error
+	DeleteBranch(ctx context.Context, org, project, repo, branchName string) error
+	CreatePullRequest(ctx context.Context, org, project, repo string, req CreatePullRequestRequest) (*PullRequest, error)
+}
+
+var _ API = (*Client)(nil)
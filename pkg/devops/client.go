@@ -6,68 +6,137 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/amaliebjorgen/fabricant/pkg/auth"
+	"github.com/amaliebjorgen/fabricant/pkg/transport"
 )
 
+// DefaultBaseURL is the Azure DevOps Services URL. {organization} is replaced per request.
+const DefaultBaseURL = "https://dev.azure.com/{organization}"
+
 // Client is the REST client for Azure DevOps APIs.
 type Client struct {
-	auth       *auth.Authenticator
+	auth       auth.TokenSource
 	httpClient *http.Client
+	baseURL    string
+	pat        string
+	scope      string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests. By default requests go through a
+// transport.RetryTransport with transport.DefaultRetryPolicy.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
 }
 
-// NewClient creates a new Azure DevOps API client.
-func NewClient(authenticator *auth.Authenticator) *Client {
-	return &Client{
-		auth:       authenticator,
-		httpClient: &http.Client{},
+// WithBaseURL sets the collection URL template, e.g. "https://{organization}.visualstudio.com"
+// for legacy URLs or "https://tfs.contoso.com/tfs/{organization}" for Azure DevOps Server, where
+// the organization name from the workspace's git connection is the collection name.
+func WithBaseURL(template string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(template, "/")
 	}
 }
 
+// WithScope sets the OAuth scope Entra tokens are requested for. Defaults to auth.DevOpsScope.
+func WithScope(scope string) Option {
+	return func(c *Client) {
+		c.scope = scope
+	}
+}
+
+// WithPersonalAccessToken authenticates with a PAT using basic auth instead of Entra tokens.
+// This is required for Azure DevOps Server.
+func WithPersonalAccessToken(pat string) Option {
+	return func(c *Client) {
+		c.pat = pat
+	}
+}
+
+// NewClient creates a new Azure DevOps API client. tokens may be nil if a personal access
+// token is configured.
+func NewClient(tokens auth.TokenSource, opts ...Option) *Client {
+	c := &Client{
+		auth:       tokens,
+		httpClient: &http.Client{Transport: transport.NewRetryTransport(nil, transport.DefaultRetryPolicy)},
+		baseURL:    DefaultBaseURL,
+		scope:      auth.DevOpsScope,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
 // doRequest performs a request against the Azure DevOps REST API.
-func (c *Client) doRequest(ctx context.Context, organization, method, path string, body interface{}, out interface{}) error {
-	baseURL := fmt.Sprintf("https://dev.azure.com/%s", organization)
+func (c *Client) doRequest(ctx context.Context, organization, method, path string, body interface{}, out interface{}) (*http.Response, error) {
+	baseURL := strings.ReplaceAll(c.baseURL, "{organization}", url.PathEscape(organization))
 
 	var reqBody io.Reader
 	if body != nil {
 		b, err := json.Marshal(body)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		reqBody = bytes.NewBuffer(b)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, reqBody)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	token, err := c.auth.GetToken(ctx, []string{auth.DevOpsScope})
-	if err != nil {
-		return fmt.Errorf("getting devops token: %w", err)
+	if err := c.authorize(ctx, req); err != nil {
+		return nil, err
 	}
-
-	req.Header.Set("Authorization", "Bearer "+token.Token)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("devops API error %d: %s", resp.StatusCode, string(b))
+		return resp, newAPIError(resp, b)
+	}
+	if resp.StatusCode == http.StatusNonAuthoritativeInfo {
+		// Rejected PATs get redirected to a sign-in page instead of a 401.
+		return resp, &APIError{StatusCode: resp.StatusCode, Message: "credentials were not accepted (sign-in page returned)", ActivityId: resp.Header.Get("ActivityId")}
 	}
 
 	if out != nil && resp.StatusCode != http.StatusNoContent {
 		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
-			return err
+			return resp, err
 		}
 	}
+	return resp, nil
+}
+
+// authorize sets the Authorization header from the PAT, or from an Entra token otherwise.
+func (c *Client) authorize(ctx context.Context, req *http.Request) error {
+	if c.pat != "" {
+		req.SetBasicAuth("", c.pat)
+		return nil
+	}
+	if c.auth == nil {
+		return fmt.Errorf("no devops credentials: configure an authenticator or a personal access token")
+	}
+	token, err := c.auth.GetToken(ctx, []string{c.scope})
+	if err != nil {
+		return fmt.Errorf("getting devops token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
 	return nil
 }
 
@@ -78,30 +147,55 @@ type GitRef struct {
 }
 
 // GitRefsResponse is the response wrapper for refs list.
-type GitRefsResponse struct {
-	Count int      `json:"count"`
-	Value []GitRef `json:"value"`
+type GitRefsResponse = ListResponse[GitRef]
+
+// Refs iterates the refs of a repository whose names start with filter (e.g. "heads/main"),
+// following x-ms-continuationtoken across pages.
+func (c *Client) Refs(ctx context.Context, org, project, repo, filter string) iter.Seq2[GitRef, error] {
+	path := fmt.Sprintf("/%s/_apis/git/repositories/%s/refs?api-version=7.1", project, repo)
+	if filter != "" {
+		path += "&filter=" + url.QueryEscape(filter)
+	}
+	return listPaged[GitRef](ctx, c, org, path)
+}
+
+// findRef returns the ref with exactly the given full name. The refs filter is a prefix
+// match, so "heads/feature" would otherwise also match "heads/feature-2".
+func (c *Client) findRef(ctx context.Context, org, project, repo, fullName string) (*GitRef, error) {
+	for ref, err := range c.Refs(ctx, org, project, repo, strings.TrimPrefix(fullName, "refs/")) {
+		if err != nil {
+			return nil, err
+		}
+		if ref.Name == fullName {
+			return &ref, nil
+		}
+	}
+	return nil, nil
 }
 
 // GetBranchObjectId gets the latest commit ID (objectId) for a specific branch.
 func (c *Client) GetBranchObjectId(ctx context.Context, org, project, repo, branchName string) (string, error) {
-	// The API filter requires just the branch name prefix without "refs/heads/"
-	filterName := strings.TrimPrefix(branchName, "refs/heads/")
-	path := fmt.Sprintf("/%s/_apis/git/repositories/%s/refs?filter=heads/%s&api-version=7.1", project, repo, filterName)
-
-	var res GitRefsResponse
-	err := c.doRequest(ctx, org, http.MethodGet, path, nil, &res)
+	ref, err := c.findRef(ctx, org, project, repo, fullBranchName(branchName))
 	if err != nil {
 		return "", err
 	}
-
-	if res.Count == 0 {
-		return "", fmt.Errorf("branch %s not found in repo %s", branchName, repo)
+	if ref == nil {
+		return "", fmt.Errorf("%w: %s in repo %s", ErrBranchNotFound, branchName, repo)
 	}
+	return ref.ObjectId, nil
+}
 
-	return res.Value[0].ObjectId, nil
+// fullBranchName qualifies a branch name with refs/heads/ if needed.
+func fullBranchName(branchName string) string {
+	if strings.HasPrefix(branchName, "refs/heads/") {
+		return branchName
+	}
+	return "refs/heads/" + branchName
 }
 
+// emptyObjectId is the null object id used to create or delete refs.
+const emptyObjectId = "0000000000000000000000000000000000000000"
+
 // CreateBranchRequest represents an update refs payload.
 type GitRefUpdate struct {
 	Name        string `json:"name"`        // The branch to create (e.g. refs/heads/feature/xxx)
@@ -111,22 +205,52 @@ type GitRefUpdate struct {
 
 // CreateBranch creates a new git branch based on a commit ID.
 func (c *Client) CreateBranch(ctx context.Context, org, project, repo, newBranchName, baseObjectId string) error {
-	fullBranchName := newBranchName
-	if !strings.HasPrefix(fullBranchName, "refs/heads/") {
-		fullBranchName = "refs/heads/" + fullBranchName
-	}
-
 	updates := []GitRefUpdate{
 		{
-			Name:        fullBranchName,
-			OldObjectId: "0000000000000000000000000000000000000000",
+			Name:        fullBranchName(newBranchName),
+			OldObjectId: emptyObjectId,
 			NewObjectId: baseObjectId,
 		},
 	}
 
 	path := fmt.Sprintf("/%s/_apis/git/repositories/%s/refs?api-version=7.1", project, repo)
 
-	// Since we are creating a ref, ADO responds with an array of GitRefUpdateResult.
-	// We'll just ignore the body for now, but ensure it completes parsing.
-	return c.doRequest(ctx, org, http.MethodPost, path, updates, nil)
+	return c.updateRefs(ctx, org, path, updates, true)
+}
+
+// updateRefs posts ref updates and checks the per-ref results, since Azure DevOps reports
+// rejected updates in a successful response.
+func (c *Client) updateRefs(ctx context.Context, org, path string, updates []GitRefUpdate, creating bool) error {
+	var res ListResponse[GitRefUpdateResult]
+	if _, err := c.doRequest(ctx, org, http.MethodPost, path, updates, &res); err != nil {
+		return err
+	}
+	for _, r := range res.Value {
+		if !r.Success {
+			return &RefUpdateError{GitRefUpdateResult: r, creating: creating}
+		}
+	}
+	return nil
+}
+
+// DeleteBranch deletes a git branch. A missing branch is reported as ErrBranchNotFound.
+func (c *Client) DeleteBranch(ctx context.Context, org, project, repo, branchName string) error {
+	ref, err := c.findRef(ctx, org, project, repo, fullBranchName(branchName))
+	if err != nil {
+		return err
+	}
+	if ref == nil {
+		return fmt.Errorf("%w: %s in repo %s", ErrBranchNotFound, branchName, repo)
+	}
+
+	updates := []GitRefUpdate{
+		{
+			Name:        ref.Name,
+			OldObjectId: ref.ObjectId,
+			NewObjectId: emptyObjectId,
+		},
+	}
+
+	path := fmt.Sprintf("/%s/_apis/git/repositories/%s/refs?api-version=7.1", project, repo)
+	return c.updateRefs(ctx, org, path, updates, false)
 }
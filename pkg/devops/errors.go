@@ -0,0 +1,93 @@
+package devops
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrBranchAlreadyExists is matched by errors.Is when creating a branch that already exists.
+var ErrBranchAlreadyExists = errors.New("branch already exists")
+
+// ErrBranchNotFound is matched by errors.Is when resolving or deleting a branch that does not exist.
+var ErrBranchNotFound = errors.New("branch not found")
+
+// APIError is returned for Azure DevOps responses with a 4xx or 5xx status.
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"message"`
+	TypeName   string `json:"typeName"`
+	// TypeKey identifies the server exception, e.g. "GitRepositoryNotFoundException".
+	TypeKey   string `json:"typeKey"`
+	ErrorCode int    `json:"errorCode"`
+	EventId   int    `json:"eventId"`
+	// ActivityId is the request id Azure DevOps support asks for.
+	ActivityId string `json:"-"`
+	// Body holds the raw response when it was not an Azure DevOps error document.
+	Body string `json:"-"`
+}
+
+// maxErrorBody bounds how much of a raw response body APIError.Error includes.
+const maxErrorBody = 512
+
+func (e *APIError) Error() string {
+	detail := e.Message
+	if detail == "" {
+		detail = truncate(strings.TrimSpace(e.Body), maxErrorBody)
+	}
+	switch {
+	case e.TypeKey == "" && detail == "":
+		return fmt.Sprintf("devops API error %d: %s", e.StatusCode, http.StatusText(e.StatusCode))
+	case e.TypeKey == "":
+		return fmt.Sprintf("devops API error %d: %s", e.StatusCode, detail)
+	}
+	return fmt.Sprintf("devops API error %d: [%s] %s", e.StatusCode, e.TypeKey, detail)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{}
+	if err := json.Unmarshal(body, e); err != nil || (e.TypeKey == "" && e.Message == "") {
+		*e = APIError{Body: string(body)}
+	}
+	e.StatusCode = resp.StatusCode
+	e.ActivityId = resp.Header.Get("ActivityId")
+	return e
+}
+
+// GitRefUpdateResult is the per-ref outcome of a refs update.
+type GitRefUpdateResult struct {
+	Name          string `json:"name"`
+	OldObjectId   string `json:"oldObjectId"`
+	NewObjectId   string `json:"newObjectId"`
+	Success       bool   `json:"success"`
+	UpdateStatus  string `json:"updateStatus"`
+	CustomMessage string `json:"customMessage,omitempty"`
+}
+
+// RefUpdateError is returned when Azure DevOps accepts a refs update but rejects a ref in it.
+type RefUpdateError struct {
+	GitRefUpdateResult
+	creating bool
+}
+
+func (e *RefUpdateError) Error() string {
+	msg := fmt.Sprintf("updating ref %s failed: %s", e.Name, e.UpdateStatus)
+	if e.CustomMessage != "" {
+		msg += ": " + e.CustomMessage
+	}
+	return msg
+}
+
+// Is reports a stale old object id on creation as ErrBranchAlreadyExists.
+func (e *RefUpdateError) Is(target error) bool {
+	return target == ErrBranchAlreadyExists && e.creating && e.UpdateStatus == "staleOldObjectId"
+}
@@ -0,0 +1,54 @@
+package devops
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAPIErrorMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{
+			name:   "error document",
+			status: http.StatusNotFound,
+			body:   `{"typeKey":"GitRepositoryNotFoundException","message":"TF401019: The Git repository does not exist."}`,
+			want:   "devops API error 404: [GitRepositoryNotFoundException] TF401019: The Git repository does not exist.",
+		},
+		{
+			name:   "message without type key",
+			status: http.StatusBadRequest,
+			body:   `{"message":"Invalid ref name"}`,
+			want:   "devops API error 400: Invalid ref name",
+		},
+		{
+			name:   "plain body",
+			status: http.StatusServiceUnavailable,
+			body:   "service unavailable\n",
+			want:   "devops API error 503: service unavailable",
+		},
+		{
+			name:   "empty body",
+			status: http.StatusUnauthorized,
+			want:   "devops API error 401: Unauthorized",
+		},
+		{
+			name:   "long html body",
+			status: http.StatusBadGateway,
+			body:   "<html>" + strings.Repeat("x", maxErrorBody),
+			want:   "devops API error 502: <html>" + strings.Repeat("x", maxErrorBody-len("<html>")) + "...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if got := newAPIError(resp, []byte(tt.body)).Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package devops
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+)
+
+// continuationHeader carries the token for the next page of Azure DevOps list responses.
+const continuationHeader = "x-ms-continuationtoken"
+
+// ListResponse is the envelope of Azure DevOps list responses.
+type ListResponse[T any] struct {
+	Count int `json:"count"`
+	Value []T `json:"value"`
+}
+
+// listPaged iterates every item of a list endpoint, passing the continuation token of each
+// response back as the continuationToken query parameter. The iteration stops after yielding
+// the first error.
+func listPaged[T any](ctx context.Context, c *Client, org, path string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		next := path
+		for {
+			var page ListResponse[T]
+			resp, err := c.doRequest(ctx, org, http.MethodGet, next, nil, &page)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, v := range page.Value {
+				if !yield(v, nil) {
+					return
+				}
+			}
+			token := resp.Header.Get(continuationHeader)
+			if token == "" {
+				return
+			}
+			next = path + "&continuationToken=" + url.QueryEscape(token)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package devops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// CreatePullRequestRequest opens a pull request from SourceRefName into TargetRefName.
+type CreatePullRequestRequest struct {
+	SourceRefName string `json:"sourceRefName"`
+	TargetRefName string `json:"targetRefName"`
+	Title         string `json:"title"`
+	Description   string `json:"description,omitempty"`
+	IsDraft       bool   `json:"isDraft,omitempty"`
+}
+
+// PullRequest is a created pull request.
+type PullRequest struct {
+	PullRequestId int    `json:"pullRequestId"`
+	URL           string `json:"url"`
+	Repository    struct {
+		WebURL string `json:"webUrl"`
+	} `json:"repository"`
+}
+
+// WebURL returns the link to the pull request in the Azure DevOps portal.
+func (pr PullRequest) WebURL() string {
+	return fmt.Sprintf("%s/pullrequest/%d", pr.Repository.WebURL, pr.PullRequestId)
+}
+
+// CreatePullRequest opens a pull request.
+func (c *Client) CreatePullRequest(ctx context.Context, org, project, repo string, req CreatePullRequestRequest) (*PullRequest, error) {
+	req.SourceRefName = fullBranchName(req.SourceRefName)
+	req.TargetRefName = fullBranchName(req.TargetRefName)
+	path := fmt.Sprintf("/%s/_apis/git/repositories/%s/pullrequests?api-version=7.1", project, repo)
+	var pr PullRequest
+	if _, err := c.doRequest(ctx, org, http.MethodPost, path, req, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
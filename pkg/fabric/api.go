@@ -0,0 +1,40 @@
+package fabric
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+)
+
+// API is the set of Fabric operations implemented by *Client. Code that depends on API rather
+// than *Client can be tested against a fake, or against a Client pointed at a fabrictest.Server.
+type API interface {
+	GetWorkspace(ctx context.Context, id string) (*Workspace, error)
+	Workspaces(ctx context.Context) iter.Seq2[Workspace, error]
+	ListWorkspaces(ctx context.Context) ([]Workspace, error)
+	CreateWorkspace(ctx context.Context, req CreateWorkspaceRequest) (*Workspace, error)
+	DeleteWorkspace(ctx context.Context, id string) error
+
+	GetGitConnection(ctx context.Context, id string) (*GetGitConnectionResponse, error)
+	GetGitStatus(ctx context.Context, id string) (*GitStatus, error)
+	GetMyGitCredentials(ctx context.Context, workspaceId string) (*GitCredentials, error)
+	ConnectWorkspaceToGit(ctx context.Context, workspaceId string, req ConnectToGitRequest) error
+	InitializeGitConnection(ctx context.Context, workspaceId string, req InitializeGitConnectionRequest) (*InitializeGitConnectionResponse, error)
+	UpdateWorkspaceFromGit(ctx context.Context, workspaceId string, workspaceHead string, remoteCommitHash string) (string, error)
+	CommitToGit(ctx context.Context, workspaceId string, req CommitToGitRequest) error
+
+	Items(ctx context.Context, workspaceId, itemType string) iter.Seq2[Item, error]
+	ListItems(ctx context.Context, workspaceId, itemType string) ([]Item, error)
+	GetItem(ctx context.Context, workspaceId, itemId string) (*Item, error)
+	CreateItem(ctx context.Context, workspaceId string, req CreateItemRequest) (*Item, error)
+	UpdateItem(ctx context.Context, workspaceId, itemId string, req UpdateItemRequest) (*Item, error)
+	DeleteItem(ctx context.Context, workspaceId, itemId string) error
+	GetItemDefinition(ctx context.Context, workspaceId, itemId, format string) (*ItemDefinition, error)
+	UpdateItemDefinition(ctx context.Context, workspaceId, itemId string, def ItemDefinition) error
+
+	GetOperationStatus(ctx context.Context, operationId string) (*OperationStatus, error)
+	GetOperationResult(ctx context.Context, operationId string) (json.RawMessage, error)
+	WaitForOperation(ctx context.Context, operationId string, opts *WaitOptions) (json.RawMessage, error)
+}
+
+var _ API = (*Client)(nil)
@@ -6,25 +6,76 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/amaliebjorgen/fabricant/pkg/auth"
+	"github.com/amaliebjorgen/fabricant/pkg/transport"
 )
 
+// BaseURL is the default Fabric REST API endpoint.
 const BaseURL = "https://api.fabric.microsoft.com/v1"
 
 // Client is the REST client for Microsoft Fabric APIs.
 type Client struct {
-	auth       *auth.Authenticator
+	auth       auth.TokenSource
 	httpClient *http.Client
+	baseURL    string
+	scope      string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests. By default requests go through a
+// transport.RetryTransport with transport.DefaultRetryPolicy.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithBaseURL sets the API endpoint, e.g. for a sovereign cloud or the URL of a
+// fabrictest.Server. Unless WithScope is given, tokens are requested for the endpoint's host.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithScope sets the OAuth scope tokens are requested for.
+func WithScope(scope string) Option {
+	return func(c *Client) {
+		c.scope = scope
+	}
+}
+
+// scopeForURL returns the ".default" scope of the host serving baseURL, which for BaseURL is
+// auth.FabricScope.
+func scopeForURL(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return auth.FabricScope
+	}
+	return u.Scheme + "://" + u.Host + "/.default"
 }
 
 // NewClient creates a new Fabric API client.
-func NewClient(authenticator *auth.Authenticator) *Client {
-	return &Client{
-		auth:       authenticator,
-		httpClient: &http.Client{},
+func NewClient(tokens auth.TokenSource, opts ...Option) *Client {
+	c := &Client{
+		auth:       tokens,
+		httpClient: &http.Client{Transport: transport.NewRetryTransport(nil, transport.DefaultRetryPolicy)},
+		baseURL:    BaseURL,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	if c.scope == "" {
+		c.scope = scopeForURL(c.baseURL)
+	}
+	return c
 }
 
 // doRequest performs a request against the Fabric API.
@@ -38,12 +89,12 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 		reqBody = bytes.NewBuffer(b)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, BaseURL+path, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
 	if err != nil {
 		return nil, err
 	}
 
-	token, err := c.auth.GetToken(ctx, []string{auth.FabricScope})
+	token, err := c.auth.GetToken(ctx, []string{c.scope})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get fabric auth token: %w", err)
 	}
@@ -60,7 +111,7 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 
 	if resp.StatusCode >= 400 {
 		b, _ := io.ReadAll(resp.Body)
-		return resp, fmt.Errorf("fabric API error %d: %s", resp.StatusCode, string(b))
+		return resp, newAPIError(resp, b)
 	}
 
 	if out != nil && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusAccepted {
@@ -71,10 +122,19 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 	return resp, nil
 }
 
-// GitProviderDetails holds the configuration for a workspace's git connection.
+// Git provider types supported by Fabric git integration.
+const (
+	GitProviderAzureDevOps = "AzureDevOps"
+	GitProviderGitHub      = "GitHub"
+)
+
+// GitProviderDetails holds the configuration for a workspace's git connection. Azure DevOps
+// connections use OrganizationName and ProjectName, GitHub connections use OwnerName.
 type GitProviderDetails struct {
-	OrganizationName string `json:"organizationName"`
-	ProjectName      string `json:"projectName"`
+	OrganizationName string `json:"organizationName,omitempty"`
+	ProjectName      string `json:"projectName,omitempty"`
+	OwnerName        string `json:"ownerName,omitempty"`
+	CustomDomainName string `json:"customDomainName,omitempty"`
 	RepositoryName   string `json:"repositoryName"`
 	BranchName       string `json:"branchName"`
 	DirectoryName    string `json:"directoryName"`
@@ -125,16 +185,48 @@ func (c *Client) GetGitConnection(ctx context.Context, id string) (*GetGitConnec
 
 // GitStatus represents the current sync status of the workspace with Git.
 type GitStatus struct {
-	RemoteCommitHash string `json:"remoteCommitHash"`
-	WorkspaceHead    string `json:"workspaceHead"`
+	RemoteCommitHash string       `json:"remoteCommitHash"`
+	WorkspaceHead    string       `json:"workspaceHead"`
+	Changes          []ItemChange `json:"changes,omitempty"`
+}
+
+// Change kinds reported in ItemChange.WorkspaceChange and RemoteChange.
+const (
+	ChangeAdded    = "Added"
+	ChangeModified = "Modified"
+	ChangeDeleted  = "Deleted"
+)
+
+// ConflictTypeConflict marks an item changed on both sides.
+const ConflictTypeConflict = "Conflict"
+
+// ItemChange is an item that differs between the workspace and its git branch.
+type ItemChange struct {
+	ItemMetadata    ItemMetadata `json:"itemMetadata"`
+	WorkspaceChange string       `json:"workspaceChange,omitempty"`
+	RemoteChange    string       `json:"remoteChange,omitempty"`
+	ConflictType    string       `json:"conflictType,omitempty"`
+}
+
+// ItemMetadata identifies the item of an ItemChange.
+type ItemMetadata struct {
+	ItemIdentifier ItemIdentifier `json:"itemIdentifier"`
+	ItemType       string         `json:"itemType"`
+	DisplayName    string         `json:"displayName"`
 }
 
-// GetGitStatus calls GET /workspaces/{workspaceId}/git/status
+// ItemIdentifier holds an item's workspace id (ObjectId) and its id in git (LogicalId).
+// ObjectId is empty for items that only exist in git.
+type ItemIdentifier struct {
+	ObjectId  string `json:"objectId,omitempty"`
+	LogicalId string `json:"logicalId,omitempty"`
+}
+
+// GetGitStatus calls GET /workspaces/{workspaceId}/git/status. The status may be computed as a
+// long-running operation, in which case it is waited for.
 func (c *Client) GetGitStatus(ctx context.Context, id string) (*GitStatus, error) {
 	var resp GitStatus
-	// We might need to wait for the status to initialize or compute, it returns 200/202.
-	_, err := c.doRequest(ctx, http.MethodGet, "/workspaces/"+id+"/git/status", nil, &resp)
-	if err != nil {
+	if err := c.doLongRunningRequest(ctx, http.MethodGet, "/workspaces/"+id+"/git/status", nil, &resp, nil); err != nil {
 		return nil, err
 	}
 	return &resp, nil
@@ -150,9 +242,40 @@ func (c *Client) CreateWorkspace(ctx context.Context, req CreateWorkspaceRequest
 	return &ws, nil
 }
 
+// DeleteWorkspace calls DELETE /workspaces/{workspaceId}
+func (c *Client) DeleteWorkspace(ctx context.Context, id string) error {
+	_, err := c.doRequest(ctx, http.MethodDelete, "/workspaces/"+id, nil, nil)
+	return err
+}
+
+// Git credential sources.
+const (
+	GitCredentialsAutomatic            = "Automatic"
+	GitCredentialsConfiguredConnection = "ConfiguredConnection"
+	GitCredentialsNone                 = "None"
+)
+
+// GitCredentials describes how Fabric authenticates to the git provider. GitHub connections
+// require a ConfiguredConnection referring to a Fabric connection by id.
+type GitCredentials struct {
+	Source       string `json:"source"`
+	ConnectionId string `json:"connectionId,omitempty"`
+}
+
+// GetMyGitCredentials calls GET /workspaces/{workspaceId}/git/myGitCredentials
+func (c *Client) GetMyGitCredentials(ctx context.Context, workspaceId string) (*GitCredentials, error) {
+	var resp GitCredentials
+	_, err := c.doRequest(ctx, http.MethodGet, "/workspaces/"+workspaceId+"/git/myGitCredentials", nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // ConnectToGitRequest connects a workspace to git.
 type ConnectToGitRequest struct {
 	GitProviderDetails *GitProviderDetails `json:"gitProviderDetails"`
+	MyGitCredentials   *GitCredentials     `json:"myGitCredentials,omitempty"`
 }
 
 // ConnectWorkspaceToGit links a workspace to a git repository and branch.
@@ -162,12 +285,41 @@ func (c *Client) ConnectWorkspaceToGit(ctx context.Context, workspaceId string,
 	return err
 }
 
-// InitializeGitConnection initializes the git connection for a workspace.
-func (c *Client) InitializeGitConnection(ctx context.Context, workspaceId string) error {
+// Initialization strategies for InitializeGitConnection.
+const (
+	InitializationStrategyNone            = "None"
+	InitializationStrategyPreferRemote    = "PreferRemote"
+	InitializationStrategyPreferWorkspace = "PreferWorkspace"
+)
+
+// Required actions returned by InitializeGitConnection.
+const (
+	RequiredActionNone          = "None"
+	RequiredActionUpdateFromGit = "UpdateFromGit"
+	RequiredActionCommitToGit   = "CommitToGit"
+)
+
+// InitializeGitConnectionRequest is the payload for initializing a git connection.
+type InitializeGitConnectionRequest struct {
+	InitializationStrategy string `json:"initializationStrategy,omitempty"`
+}
+
+// InitializeGitConnectionResponse tells the caller what is needed to bring the workspace in sync.
+type InitializeGitConnectionResponse struct {
+	RequiredAction   string `json:"requiredAction"`
+	WorkspaceHead    string `json:"workspaceHead,omitempty"`
+	RemoteCommitHash string `json:"remoteCommitHash,omitempty"`
+}
+
+// InitializeGitConnection initializes the git connection for a workspace. The call may be
+// long-running, in which case it waits for the operation and returns its result.
+func (c *Client) InitializeGitConnection(ctx context.Context, workspaceId string, req InitializeGitConnectionRequest) (*InitializeGitConnectionResponse, error) {
 	path := fmt.Sprintf("/workspaces/%s/git/initializeConnection", workspaceId)
-	req := map[string]interface{}{}
-	_, err := c.doRequest(ctx, http.MethodPost, path, req, nil)
-	return err
+	var resp InitializeGitConnectionResponse
+	if err := c.doLongRunningRequest(ctx, http.MethodPost, path, req, &resp, nil); err != nil {
+		return nil, err
+	}
+	return &resp, nil
 }
 
 // UpdateWorkspaceFromGit updates the workspace items from the linked git branch. Returns operation ID empty string if not long-running.
@@ -194,39 +346,42 @@ func (c *Client) UpdateWorkspaceFromGit(ctx context.Context, workspaceId string,
 		return "", err
 	}
 
-	operationId := resp.Header.Get("x-ms-operation-id")
-	return operationId, nil
+	if resp.StatusCode != http.StatusAccepted {
+		return "", nil
+	}
+	return operationIdFromResponse(resp), nil
 }
 
-// Connection represents a connection to a data source, e.g., Lakehouse.
-type Connection struct {
-	Id          string `json:"id"`
-	DisplayName string `json:"displayName"`
-}
+// Commit modes for CommitToGit.
+const (
+	CommitModeAll       = "All"
+	CommitModeSelective = "Selective"
+)
 
-// UpdateConnectionRequest allows patching a connection mapping.
-type UpdateConnectionRequest struct {
-	UpdateDetails map[string]interface{} `json:"updateDetails"`
+// CommitToGitRequest is the payload for committing workspace changes to the connected branch.
+// Items is only used with CommitModeSelective. WorkspaceHead is the head from GetGitStatus, so
+// the commit fails if the workspace has been synced since.
+type CommitToGitRequest struct {
+	Mode          string           `json:"mode"`
+	WorkspaceHead string           `json:"workspaceHead,omitempty"`
+	Comment       string           `json:"comment,omitempty"`
+	Items         []ItemIdentifier `json:"items,omitempty"`
 }
 
-// UpdateConnections is a placeholder to patch hardcoded Lakehouse connections.
-// The actual API endpoint might require retrieving connections and patching them one by one.
-func (c *Client) UpdateConnections(ctx context.Context, workspaceId string, mappings map[string]string) error {
-	// TODO: Replace with the actual iterate and update logic depending on Lakehouse binding types in Fabric.
-	// We'll iterate the items/connections and redirect them to the new workspace's lakehouse.
-	// e.g., foreach conn in mapping: _, err = c.doRequest(ctx, "PATCH", "/connections/"+conn.Id, UpdateDetails, nil)
-	return nil
+// CommitToGit commits workspace changes to the connected git branch. The call may be
+// long-running, in which case it waits for the operation.
+func (c *Client) CommitToGit(ctx context.Context, workspaceId string, req CommitToGitRequest) error {
+	path := fmt.Sprintf("/workspaces/%s/git/commitToGit", workspaceId)
+	return c.doLongRunningRequest(ctx, http.MethodPost, path, req, nil, nil)
 }
 
 // OperationStatus represents the response from the Fabric Operations API.
 type OperationStatus struct {
-	Status      string `json:"status"` // e.g. "NotStarted", "Running", "Succeeded", "Failed"
-	CreatedTime string `json:"createdTime,omitempty"`
-	LastUpdated string `json:"lastUpdatedTime,omitempty"`
-	Error       struct {
-		ErrorCode string `json:"errorCode"`
-		Message   string `json:"message"`
-	} `json:"error,omitempty"`
+	Status          string         `json:"status"` // e.g. "NotStarted", "Running", "Succeeded", "Failed"
+	CreatedTime     string         `json:"createdTime,omitempty"`
+	LastUpdated     string         `json:"lastUpdatedTime,omitempty"`
+	PercentComplete int            `json:"percentComplete"`
+	Error           *ErrorResponse `json:"error,omitempty"`
 }
 
 // GetOperationStatus calls GET /operations/{operationId}
@@ -243,17 +398,15 @@ func (c *Client) GetOperationStatus(ctx context.Context, operationId string) (*O
 	return &resp, nil
 }
 
-// WorkspaceListResponse represents the response containing an array of Workspaces.
-type WorkspaceListResponse struct {
-	Value []Workspace `json:"value"`
+// WorkspaceListResponse represents a page of Workspaces.
+type WorkspaceListResponse = Page[Workspace]
+
+// Workspaces iterates GET /workspaces across all pages.
+func (c *Client) Workspaces(ctx context.Context) iter.Seq2[Workspace, error] {
+	return listPaged[Workspace](ctx, c, "/workspaces")
 }
 
-// ListWorkspaces calls GET /workspaces
+// ListWorkspaces calls GET /workspaces and returns every page.
 func (c *Client) ListWorkspaces(ctx context.Context) ([]Workspace, error) {
-	var resp WorkspaceListResponse
-	_, err := c.doRequest(ctx, http.MethodGet, "/workspaces", nil, &resp)
-	if err != nil {
-		return nil, err
-	}
-	return resp.Value, nil
+	return collect(c.Workspaces(ctx))
 }
@@ -0,0 +1,126 @@
+package fabric
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error codes returned by Fabric APIs that callers commonly branch on.
+const (
+	ErrorCodeWorkspaceNameAlreadyExists = "WorkspaceNameAlreadyExists"
+	ErrorCodeInsufficientPrivileges     = "InsufficientPrivileges"
+	ErrorCodeWorkspaceNotFound          = "WorkspaceNotFound"
+	ErrorCodeItemNotFound               = "ItemNotFound"
+	ErrorCodeWorkspaceNotConnectedToGit = "WorkspaceNotConnectedToGit"
+	ErrorCodeOperationHasNoResult       = "OperationHasNoResult"
+)
+
+// ErrorRelatedResource identifies the resource an error refers to.
+type ErrorRelatedResource struct {
+	ResourceId   string `json:"resourceId"`
+	ResourceType string `json:"resourceType"`
+}
+
+// ErrorResponseDetails is an additional error entry in ErrorResponse.MoreDetails.
+type ErrorResponseDetails struct {
+	ErrorCode       string                `json:"errorCode"`
+	Message         string                `json:"message"`
+	RelatedResource *ErrorRelatedResource `json:"relatedResource,omitempty"`
+}
+
+// ErrorResponse is the error body returned by Fabric APIs and failed operations.
+type ErrorResponse struct {
+	ErrorCode       string                 `json:"errorCode"`
+	Message         string                 `json:"message"`
+	RequestId       string                 `json:"requestId,omitempty"`
+	MoreDetails     []ErrorResponseDetails `json:"moreDetails,omitempty"`
+	RelatedResource *ErrorRelatedResource  `json:"relatedResource,omitempty"`
+}
+
+// APIError is returned for Fabric API responses with a 4xx or 5xx status.
+type APIError struct {
+	StatusCode int
+	ErrorResponse
+	// Body holds the raw response when it was not a Fabric error document.
+	Body string
+}
+
+// maxErrorBody bounds how much of a raw response body APIError.Error includes.
+const maxErrorBody = 512
+
+func (e *APIError) Error() string {
+	detail := e.Message
+	if detail == "" {
+		detail = truncate(strings.TrimSpace(e.Body), maxErrorBody)
+	}
+	switch {
+	case e.ErrorCode == "" && detail == "":
+		return fmt.Sprintf("fabric API error %d: %s", e.StatusCode, http.StatusText(e.StatusCode))
+	case e.ErrorCode == "":
+		return fmt.Sprintf("fabric API error %d: %s", e.StatusCode, detail)
+	}
+	msg := fmt.Sprintf("fabric API error %d: [%s] %s", e.StatusCode, e.ErrorCode, detail)
+	for _, d := range e.MoreDetails {
+		msg += fmt.Sprintf("; [%s] %s", d.ErrorCode, d.Message)
+	}
+	return msg
+}
+
+// newAPIError builds an APIError from a failed response and its body.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{StatusCode: resp.StatusCode}
+	if err := json.Unmarshal(body, &e.ErrorResponse); err != nil {
+		e.ErrorResponse = ErrorResponse{}
+		e.Body = string(body)
+	}
+	if e.ErrorCode == "" {
+		e.ErrorCode = resp.Header.Get("x-ms-public-api-error-code")
+	}
+	if e.RequestId == "" {
+		e.RequestId = resp.Header.Get("requestid")
+	}
+	return e
+}
+
+// truncate shortens s to at most n bytes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// OperationError is returned when a long-running operation ends in failure.
+type OperationError struct {
+	OperationId string
+	ErrorResponse
+}
+
+func (e *OperationError) Error() string {
+	if e.ErrorCode == "" {
+		return fmt.Sprintf("operation %s failed", e.OperationId)
+	}
+	return fmt.Sprintf("operation %s failed: [%s] %s", e.OperationId, e.ErrorCode, e.Message)
+}
+
+// ErrorCode returns the Fabric error code carried by err, or "" if err is not an *APIError or
+// *OperationError.
+func ErrorCode(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode
+	}
+	var opErr *OperationError
+	if errors.As(err, &opErr) {
+		return opErr.ErrorCode
+	}
+	return ""
+}
+
+// IsErrorCode reports whether err carries the given Fabric error code.
+func IsErrorCode(err error, code string) bool {
+	return code != "" && ErrorCode(err) == code
+}
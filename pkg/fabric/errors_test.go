@@ -0,0 +1,60 @@
+package fabric
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAPIErrorMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header string
+		body   string
+		want   string
+	}{
+		{
+			name:   "error document",
+			status: http.StatusNotFound,
+			body:   `{"errorCode":"ItemNotFound","message":"The item was not found"}`,
+			want:   "fabric API error 404: [ItemNotFound] The item was not found",
+		},
+		{
+			name:   "header code with plain body",
+			status: http.StatusBadGateway,
+			header: "UpstreamFailure",
+			body:   "upstream connect error",
+			want:   "fabric API error 502: [UpstreamFailure] upstream connect error",
+		},
+		{
+			name:   "plain body",
+			status: http.StatusServiceUnavailable,
+			body:   "service unavailable\n",
+			want:   "fabric API error 503: service unavailable",
+		},
+		{
+			name:   "empty error document",
+			status: http.StatusInternalServerError,
+			body:   `{"errorCode":"","message":"","requestId":"r1"}`,
+			want:   "fabric API error 500: Internal Server Error",
+		},
+		{
+			name:   "long plain body",
+			status: http.StatusBadRequest,
+			body:   strings.Repeat("x", maxErrorBody+10),
+			want:   "fabric API error 400: " + strings.Repeat("x", maxErrorBody) + "...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("x-ms-public-api-error-code", tt.header)
+			}
+			if got := newAPIError(resp, []byte(tt.body)).Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
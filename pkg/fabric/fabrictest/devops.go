@@ -0,0 +1,137 @@
+package fabrictest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/amaliebjorgen/fabricant/pkg/devops"
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+)
+
+// emptyObjectId is the null object id Azure DevOps uses to create or delete refs.
+const emptyObjectId = "0000000000000000000000000000000000000000"
+
+// AddBranch creates or moves a branch in an Azure DevOps repository, creating the repository
+// if needed.
+func (s *Server) AddBranch(org, project, repoName, branch, commitId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.adoRepo(org, project, repoName, true).branches[strings.TrimPrefix(branch, "refs/heads/")] = commitId
+}
+
+// Branches returns the branches of an Azure DevOps repository mapped to their commit ids.
+func (s *Server) Branches(org, project, repoName string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := map[string]string{}
+	if rp := s.adoRepo(org, project, repoName, false); rp != nil {
+		for b, c := range rp.branches {
+			out[b] = c
+		}
+	}
+	return out
+}
+
+// PullRequests returns the pull requests created in an Azure DevOps repository.
+func (s *Server) PullRequests(org, project, repoName string) []devops.CreatePullRequestRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rp := s.adoRepo(org, project, repoName, false); rp != nil {
+		return append([]devops.CreatePullRequestRequest(nil), rp.pullRequests...)
+	}
+	return nil
+}
+
+func (s *Server) adoRepo(org, project, repoName string, create bool) *repo {
+	key := strings.ToLower(org + "/" + project + "/" + repoName)
+	rp := s.repos[key]
+	if rp == nil && create {
+		rp = &repo{branches: map[string]string{}}
+		s.repos[key] = rp
+	}
+	return rp
+}
+
+// repo returns the repository a git connection refers to. Only Azure DevOps repositories are
+// simulated; other providers get an empty repository.
+func (s *Server) repo(git fabric.GitProviderDetails) *repo {
+	if git.GitProviderType == fabric.GitProviderAzureDevOps {
+		if rp := s.adoRepo(git.OrganizationName, git.ProjectName, git.RepositoryName, false); rp != nil {
+			return rp
+		}
+	}
+	return &repo{branches: map[string]string{}}
+}
+
+func (s *Server) registerDevOps(mux *http.ServeMux) {
+	const prefix = "/devops/{org}/{project}/_apis/git/repositories/{repo}"
+	mux.HandleFunc("GET "+prefix+"/refs", s.withRepo(s.listRefs))
+	mux.HandleFunc("POST "+prefix+"/refs", s.withRepo(s.updateRefs))
+	mux.HandleFunc("POST "+prefix+"/pullrequests", s.withRepo(s.createPullRequest))
+}
+
+func (s *Server) withRepo(h func(http.ResponseWriter, *http.Request, *repo)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rp := s.adoRepo(r.PathValue("org"), r.PathValue("project"), r.PathValue("repo"), false)
+		if rp == nil {
+			writeDevOpsError(w, http.StatusNotFound, "GitRepositoryNotFoundException", "TF401019: The Git repository does not exist")
+			return
+		}
+		h(w, r, rp)
+	}
+}
+
+func (s *Server) listRefs(w http.ResponseWriter, r *http.Request, rp *repo) {
+	filter := "refs/" + r.URL.Query().Get("filter")
+	refs := devops.ListResponse[devops.GitRef]{Value: []devops.GitRef{}}
+	for b, c := range rp.branches {
+		if name := "refs/heads/" + b; strings.HasPrefix(name, filter) {
+			refs.Value = append(refs.Value, devops.GitRef{Name: name, ObjectId: c})
+		}
+	}
+	refs.Count = len(refs.Value)
+	writeJSON(w, http.StatusOK, refs)
+}
+
+func (s *Server) updateRefs(w http.ResponseWriter, r *http.Request, rp *repo) {
+	var updates []devops.GitRefUpdate
+	if !decode(w, r, &updates) {
+		return
+	}
+	res := devops.ListResponse[devops.GitRefUpdateResult]{}
+	for _, u := range updates {
+		branch := strings.TrimPrefix(u.Name, "refs/heads/")
+		current, exists := rp.branches[branch]
+		result := devops.GitRefUpdateResult{Name: u.Name, OldObjectId: u.OldObjectId, NewObjectId: u.NewObjectId, UpdateStatus: "succeeded", Success: true}
+		switch {
+		case u.OldObjectId == emptyObjectId && exists, u.OldObjectId != emptyObjectId && u.OldObjectId != current:
+			result.UpdateStatus = "staleOldObjectId"
+			result.Success = false
+		case u.NewObjectId == emptyObjectId:
+			delete(rp.branches, branch)
+		default:
+			rp.branches[branch] = u.NewObjectId
+		}
+		res.Value = append(res.Value, result)
+	}
+	res.Count = len(res.Value)
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) createPullRequest(w http.ResponseWriter, r *http.Request, rp *repo) {
+	var req devops.CreatePullRequestRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	rp.pullRequests = append(rp.pullRequests, req)
+	id := len(rp.pullRequests)
+	var pr devops.PullRequest
+	pr.PullRequestId = id
+	pr.Repository.WebURL = s.URL + "/devops/" + r.PathValue("org") + "/" + r.PathValue("project") + "/_git/" + r.PathValue("repo")
+	writeJSON(w, http.StatusCreated, pr)
+}
+
+func writeDevOpsError(w http.ResponseWriter, status int, typeKey, message string) {
+	w.Header().Set("ActivityId", "fabrictest")
+	writeJSON(w, status, devops.APIError{TypeKey: typeKey, Message: message})
+}
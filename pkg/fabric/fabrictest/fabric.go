@@ -0,0 +1,374 @@
+package fabrictest
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+)
+
+// AddWorkspace adds a workspace and returns it with its Id and Type filled in.
+func (s *Server) AddWorkspace(ws fabric.Workspace) fabric.Workspace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ws.Id == "" {
+		ws.Id = s.newId()
+	}
+	if ws.Type == "" {
+		ws.Type = "Workspace"
+	}
+	ws.GitProviderDetails = nil
+	s.workspaces = append(s.workspaces, &workspace{Workspace: ws, credentials: fabric.GitCredentials{Source: fabric.GitCredentialsAutomatic}})
+	return ws
+}
+
+// ConnectGit connects a workspace to a branch, as if it had been synced from git. GitHub
+// connections are given a configured connection credential.
+func (s *Server) ConnectGit(workspaceId string, git fabric.GitProviderDetails) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ws := s.workspace(workspaceId)
+	if ws == nil {
+		panic("fabrictest: no workspace " + workspaceId)
+	}
+	ws.git = &git
+	ws.head = s.repo(git).branches[git.BranchName]
+	if git.GitProviderType == fabric.GitProviderGitHub {
+		ws.credentials = fabric.GitCredentials{Source: fabric.GitCredentialsConfiguredConnection, ConnectionId: s.newId()}
+	}
+}
+
+// Workspace returns the workspace with the given id and its git connection, if any.
+func (s *Server) Workspace(id string) (fabric.Workspace, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ws := s.workspace(id)
+	if ws == nil {
+		return fabric.Workspace{}, false
+	}
+	return ws.view(), true
+}
+
+// Workspaces returns all workspaces in creation order.
+func (s *Server) Workspaces() []fabric.Workspace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]fabric.Workspace, len(s.workspaces))
+	for i, ws := range s.workspaces {
+		out[i] = ws.view()
+	}
+	return out
+}
+
+func (ws *workspace) view() fabric.Workspace {
+	v := ws.Workspace
+	if ws.git != nil {
+		git := *ws.git
+		v.GitProviderDetails = &git
+	}
+	return v
+}
+
+func (s *Server) workspace(id string) *workspace {
+	for _, ws := range s.workspaces {
+		if ws.Id == id {
+			return ws
+		}
+	}
+	return nil
+}
+
+func (s *Server) registerFabric(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/workspaces", s.listWorkspaces)
+	mux.HandleFunc("POST /v1/workspaces", s.createWorkspace)
+	mux.HandleFunc("GET /v1/workspaces/{id}", s.withWorkspace(s.getWorkspace))
+	mux.HandleFunc("DELETE /v1/workspaces/{id}", s.withWorkspace(s.deleteWorkspace))
+	mux.HandleFunc("GET /v1/workspaces/{id}/git/connection", s.withWorkspace(s.getGitConnection))
+	mux.HandleFunc("GET /v1/workspaces/{id}/git/status", s.withWorkspace(s.getGitStatus))
+	mux.HandleFunc("GET /v1/workspaces/{id}/git/myGitCredentials", s.withWorkspace(s.getGitCredentials))
+	mux.HandleFunc("POST /v1/workspaces/{id}/git/connect", s.withWorkspace(s.connectGit))
+	mux.HandleFunc("POST /v1/workspaces/{id}/git/initializeConnection", s.withWorkspace(s.initializeGit))
+	mux.HandleFunc("POST /v1/workspaces/{id}/git/updateFromGit", s.withWorkspace(s.updateFromGit))
+	mux.HandleFunc("POST /v1/workspaces/{id}/git/commitToGit", s.withWorkspace(s.commitToGit))
+	s.registerItems(mux)
+	mux.HandleFunc("GET /v1/operations/{id}", s.getOperation)
+	mux.HandleFunc("GET /v1/operations/{id}/result", s.getOperationResult)
+}
+
+func (s *Server) withWorkspace(h func(http.ResponseWriter, *http.Request, *workspace)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ws := s.workspace(r.PathValue("id"))
+		if ws == nil {
+			writeFabricError(w, http.StatusNotFound, fabric.ErrorCodeWorkspaceNotFound, "The requested workspace was not found")
+			return
+		}
+		h(w, r, ws)
+	}
+}
+
+func (s *Server) listWorkspaces(w http.ResponseWriter, r *http.Request) {
+	all := make([]fabric.Workspace, len(s.workspaces))
+	for i, ws := range s.workspaces {
+		all[i] = ws.Workspace
+	}
+	writePage(w, r, s.PageSize, all)
+}
+
+func (s *Server) createWorkspace(w http.ResponseWriter, r *http.Request) {
+	var req fabric.CreateWorkspaceRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	for _, ws := range s.workspaces {
+		if strings.EqualFold(ws.DisplayName, req.DisplayName) {
+			writeFabricError(w, http.StatusConflict, fabric.ErrorCodeWorkspaceNameAlreadyExists, "Workspace name already exists")
+			return
+		}
+	}
+	ws := &workspace{
+		Workspace: fabric.Workspace{
+			Id:          s.newId(),
+			DisplayName: req.DisplayName,
+			Description: req.Description,
+			Type:        "Workspace",
+			CapacityId:  req.CapacityId,
+		},
+		credentials: fabric.GitCredentials{Source: fabric.GitCredentialsAutomatic},
+	}
+	s.workspaces = append(s.workspaces, ws)
+	writeJSON(w, http.StatusCreated, ws.Workspace)
+}
+
+func (s *Server) getWorkspace(w http.ResponseWriter, r *http.Request, ws *workspace) {
+	writeJSON(w, http.StatusOK, ws.Workspace)
+}
+
+func (s *Server) deleteWorkspace(w http.ResponseWriter, r *http.Request, ws *workspace) {
+	for i, v := range s.workspaces {
+		if v == ws {
+			s.workspaces = append(s.workspaces[:i], s.workspaces[i+1:]...)
+			break
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getGitConnection(w http.ResponseWriter, r *http.Request, ws *workspace) {
+	state := "NotConnected"
+	if ws.git != nil {
+		state = "ConnectedAndInitialized"
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"gitProviderDetails": ws.git,
+		"gitConnectionState": state,
+	})
+}
+
+func (s *Server) getGitStatus(w http.ResponseWriter, r *http.Request, ws *workspace) {
+	if ws.git == nil {
+		writeFabricError(w, http.StatusBadRequest, fabric.ErrorCodeWorkspaceNotConnectedToGit, "The workspace is not connected to git")
+		return
+	}
+	status := fabric.GitStatus{
+		WorkspaceHead:    ws.head,
+		RemoteCommitHash: s.repo(*ws.git).branches[ws.git.BranchName],
+	}
+	for _, c := range ws.changes {
+		status.Changes = append(status.Changes, *c)
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) getGitCredentials(w http.ResponseWriter, r *http.Request, ws *workspace) {
+	writeJSON(w, http.StatusOK, ws.credentials)
+}
+
+func (s *Server) connectGit(w http.ResponseWriter, r *http.Request, ws *workspace) {
+	var req fabric.ConnectToGitRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if ws.git != nil {
+		writeFabricError(w, http.StatusConflict, "WorkspaceAlreadyConnectedToGit", "The workspace is already connected to git")
+		return
+	}
+	git := req.GitProviderDetails
+	if git == nil {
+		writeFabricError(w, http.StatusBadRequest, "InvalidInput", "gitProviderDetails is required")
+		return
+	}
+	if _, ok := s.repo(*git).branches[git.BranchName]; !ok && git.GitProviderType == fabric.GitProviderAzureDevOps {
+		writeFabricError(w, http.StatusBadRequest, "GitProviderResourceNotFound", "The branch "+git.BranchName+" was not found")
+		return
+	}
+	if git.GitProviderType == fabric.GitProviderGitHub {
+		c := req.MyGitCredentials
+		if c == nil || c.Source != fabric.GitCredentialsConfiguredConnection || c.ConnectionId == "" {
+			writeFabricError(w, http.StatusBadRequest, "InvalidInput", "GitHub connections require a configured connection")
+			return
+		}
+		ws.credentials = *c
+	}
+	d := *git
+	ws.git = &d
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) initializeGit(w http.ResponseWriter, r *http.Request, ws *workspace) {
+	if ws.git == nil {
+		writeFabricError(w, http.StatusBadRequest, fabric.ErrorCodeWorkspaceNotConnectedToGit, "The workspace is not connected to git")
+		return
+	}
+	remote := s.repo(*ws.git).branches[ws.git.BranchName]
+	action := fabric.RequiredActionNone
+	if ws.head != remote {
+		action = fabric.RequiredActionUpdateFromGit
+	}
+	writeJSON(w, http.StatusOK, fabric.InitializeGitConnectionResponse{
+		RequiredAction:   action,
+		WorkspaceHead:    ws.head,
+		RemoteCommitHash: remote,
+	})
+}
+
+func (s *Server) updateFromGit(w http.ResponseWriter, r *http.Request, ws *workspace) {
+	var req struct {
+		WorkspaceHead    string `json:"workspaceHead"`
+		RemoteCommitHash string `json:"remoteCommitHash"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	if ws.git == nil {
+		writeFabricError(w, http.StatusBadRequest, fabric.ErrorCodeWorkspaceNotConnectedToGit, "The workspace is not connected to git")
+		return
+	}
+	if req.WorkspaceHead != ws.head {
+		writeFabricError(w, http.StatusBadRequest, "WorkspaceHeadMismatch", "The workspace head has changed")
+		return
+	}
+	remote := req.RemoteCommitHash
+	s.startOperation(w, r, nil, func() {
+		s.syncItems(ws, remote)
+		ws.head = remote
+	})
+}
+
+// commitToGit moves the branch to a new commit holding the workspace's current items and
+// clears the committed changes.
+func (s *Server) commitToGit(w http.ResponseWriter, r *http.Request, ws *workspace) {
+	var req fabric.CommitToGitRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if ws.git == nil {
+		writeFabricError(w, http.StatusBadRequest, fabric.ErrorCodeWorkspaceNotConnectedToGit, "The workspace is not connected to git")
+		return
+	}
+	if req.WorkspaceHead != "" && req.WorkspaceHead != ws.head {
+		writeFabricError(w, http.StatusBadRequest, "WorkspaceHeadMismatch", "The workspace head has changed")
+		return
+	}
+	committed := map[*fabric.ItemChange]bool{}
+	for _, c := range ws.changes {
+		switch req.Mode {
+		case fabric.CommitModeAll:
+			committed[c] = true
+		case fabric.CommitModeSelective:
+			for _, id := range req.Items {
+				if id.ObjectId == c.ItemMetadata.ItemIdentifier.ObjectId {
+					committed[c] = true
+				}
+			}
+		default:
+			writeFabricError(w, http.StatusBadRequest, "InvalidInput", "Invalid commit mode "+req.Mode)
+			return
+		}
+	}
+	if len(committed) == 0 {
+		writeFabricError(w, http.StatusBadRequest, "NoChangesToCommit", "There are no changes to commit")
+		return
+	}
+	git := *ws.git
+	s.startOperation(w, r, nil, func() {
+		commit := strings.ReplaceAll(s.newId(), "-", "")
+		s.repo(git).branches[git.BranchName] = commit
+		ws.head = commit
+		ws.changes = slices.DeleteFunc(ws.changes, func(c *fabric.ItemChange) bool { return committed[c] })
+	})
+}
+
+// startOperation responds 202 with a new operation that runs done when it succeeds.
+func (s *Server) startOperation(w http.ResponseWriter, r *http.Request, result interface{}, done func()) {
+	op := &operation{polls: s.OperationPolls, result: result, done: done}
+	if f := s.matchFault(r, true); f != nil {
+		op.err = &fabric.ErrorResponse{ErrorCode: f.ErrorCode, Message: f.Message}
+	}
+	id := s.newId()
+	s.operations[id] = op
+	w.Header().Set("x-ms-operation-id", id)
+	w.Header().Set("Location", s.FabricURL()+"/operations/"+id)
+	s.setRetryAfter(w)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) getOperation(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	op := s.operations[id]
+	if op == nil {
+		writeFabricError(w, http.StatusNotFound, "OperationNotFound", "The operation was not found")
+		return
+	}
+	status := fabric.OperationStatus{Status: fabric.OperationRunning}
+	switch {
+	case op.polls > 0:
+		status.PercentComplete = 100 / (op.polls + 1)
+		op.polls--
+		s.setRetryAfter(w)
+	case op.err != nil:
+		status.Status = fabric.OperationFailed
+		status.Error = op.err
+	default:
+		status.Status = fabric.OperationSucceeded
+		status.PercentComplete = 100
+		if op.done != nil {
+			op.done()
+			op.done = nil
+		}
+		if op.result != nil {
+			w.Header().Set("Location", s.FabricURL()+"/operations/"+id+"/result")
+		}
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) setRetryAfter(w http.ResponseWriter) {
+	if s.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(s.RetryAfter))
+	}
+}
+
+func (s *Server) getOperationResult(w http.ResponseWriter, r *http.Request) {
+	op := s.operations[r.PathValue("id")]
+	if op == nil || op.result == nil {
+		writeFabricError(w, http.StatusNotFound, "OperationNotFound", "The operation has no result")
+		return
+	}
+	writeJSON(w, http.StatusOK, op.result)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeFabricError(w, http.StatusBadRequest, "InvalidInput", err.Error())
+		return false
+	}
+	return true
+}
+
+func writeFabricError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("requestid", "fabrictest")
+	writeJSON(w, status, fabric.ErrorResponse{ErrorCode: code, Message: message, RequestId: "fabrictest"})
+}
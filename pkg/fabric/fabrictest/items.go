@@ -0,0 +1,260 @@
+package fabrictest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+)
+
+type item struct {
+	fabric.Item
+	definition fabric.ItemDefinition
+}
+
+// AddItem adds an item to a workspace with a definition built from parts, which map file
+// paths to their content, and returns it with its Id filled in.
+func (s *Server) AddItem(workspaceId string, it fabric.Item, parts map[string]string) fabric.Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ws := s.workspace(workspaceId)
+	if ws == nil {
+		panic("fabrictest: no workspace " + workspaceId)
+	}
+	if it.Id == "" {
+		it.Id = s.newId()
+	}
+	it.WorkspaceId = workspaceId
+	var def fabric.ItemDefinition
+	for path, content := range parts {
+		def.Parts = append(def.Parts, fabric.NewItemDefinitionPart(path, []byte(content)))
+	}
+	ws.items = append(ws.items, &item{Item: it, definition: def})
+	return it
+}
+
+// Items returns the items of a workspace.
+func (s *Server) Items(workspaceId string) []fabric.Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []fabric.Item
+	if ws := s.workspace(workspaceId); ws != nil {
+		for _, it := range ws.items {
+			out = append(out, it.Item)
+		}
+	}
+	return out
+}
+
+// ItemDefinition returns the decoded definition parts of an item, keyed by path.
+func (s *Server) ItemDefinition(workspaceId, itemId string) (map[string]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ws := s.workspace(workspaceId)
+	if ws == nil {
+		return nil, false
+	}
+	it := ws.item(itemId)
+	if it == nil {
+		return nil, false
+	}
+	parts := map[string]string{}
+	for _, p := range it.definition.Parts {
+		b, _ := p.Decode()
+		parts[p.Path] = string(b)
+	}
+	return parts, true
+}
+
+// recordChange notes an uncommitted change to an item of a workspace connected to git. A new
+// item stays Added until it is committed, and deleting it drops the change.
+func (ws *workspace) recordChange(it fabric.Item, kind string) {
+	if ws.git == nil {
+		return
+	}
+	for i, c := range ws.changes {
+		if c.ItemMetadata.ItemIdentifier.ObjectId != it.Id {
+			continue
+		}
+		switch {
+		case c.WorkspaceChange == fabric.ChangeAdded && kind == fabric.ChangeDeleted:
+			ws.changes = append(ws.changes[:i], ws.changes[i+1:]...)
+		case c.WorkspaceChange != fabric.ChangeAdded:
+			c.WorkspaceChange = kind
+		}
+		c.ItemMetadata.DisplayName = it.DisplayName
+		return
+	}
+	ws.changes = append(ws.changes, &fabric.ItemChange{
+		ItemMetadata: fabric.ItemMetadata{
+			ItemIdentifier: fabric.ItemIdentifier{ObjectId: it.Id},
+			ItemType:       it.Type,
+			DisplayName:    it.DisplayName,
+		},
+		WorkspaceChange: kind,
+		ConflictType:    "None",
+	})
+}
+
+func (ws *workspace) item(id string) *item {
+	for _, it := range ws.items {
+		if it.Id == id {
+			return it
+		}
+	}
+	return nil
+}
+
+// syncItems replaces the items of ws with copies of those of another workspace on the same
+// repository at commit, standing in for the content of the commit. The copies get new ids but
+// keep their definitions, including references to the source workspace's items.
+func (s *Server) syncItems(ws *workspace, commit string) {
+	for _, src := range s.workspaces {
+		if src == ws || src.git == nil || src.head != commit || !sameRepo(*src.git, *ws.git) {
+			continue
+		}
+		ws.items = nil
+		ws.changes = nil
+		for _, it := range src.items {
+			c := &item{Item: it.Item, definition: it.definition}
+			c.Id = s.newId()
+			c.WorkspaceId = ws.Id
+			c.definition.Parts = append([]fabric.ItemDefinitionPart(nil), it.definition.Parts...)
+			ws.items = append(ws.items, c)
+		}
+		return
+	}
+}
+
+func sameRepo(a, b fabric.GitProviderDetails) bool {
+	return a.GitProviderType == b.GitProviderType &&
+		strings.EqualFold(a.OrganizationName, b.OrganizationName) &&
+		strings.EqualFold(a.ProjectName, b.ProjectName) &&
+		strings.EqualFold(a.OwnerName, b.OwnerName) &&
+		strings.EqualFold(a.RepositoryName, b.RepositoryName) &&
+		a.DirectoryName == b.DirectoryName
+}
+
+func (s *Server) registerItems(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/workspaces/{id}/items", s.withWorkspace(s.listItems))
+	mux.HandleFunc("POST /v1/workspaces/{id}/items", s.withWorkspace(s.createItem))
+	mux.HandleFunc("GET /v1/workspaces/{id}/items/{itemId}", s.withItem(s.getItem))
+	mux.HandleFunc("PATCH /v1/workspaces/{id}/items/{itemId}", s.withItem(s.updateItem))
+	mux.HandleFunc("DELETE /v1/workspaces/{id}/items/{itemId}", s.withItem(s.deleteItem))
+	mux.HandleFunc("POST /v1/workspaces/{id}/items/{itemId}/getDefinition", s.withItem(s.getItemDefinition))
+	mux.HandleFunc("POST /v1/workspaces/{id}/items/{itemId}/updateDefinition", s.withItem(s.updateItemDefinition))
+}
+
+func (s *Server) withItem(h func(http.ResponseWriter, *http.Request, *workspace, *item)) http.HandlerFunc {
+	return s.withWorkspace(func(w http.ResponseWriter, r *http.Request, ws *workspace) {
+		it := ws.item(r.PathValue("itemId"))
+		if it == nil {
+			writeFabricError(w, http.StatusNotFound, fabric.ErrorCodeItemNotFound, "The requested item was not found")
+			return
+		}
+		h(w, r, ws, it)
+	})
+}
+
+func (s *Server) listItems(w http.ResponseWriter, r *http.Request, ws *workspace) {
+	itemType := r.URL.Query().Get("type")
+	var all []fabric.Item
+	for _, it := range ws.items {
+		if itemType == "" || strings.EqualFold(it.Type, itemType) {
+			all = append(all, it.Item)
+		}
+	}
+	writePage(w, r, s.PageSize, all)
+}
+
+func (s *Server) createItem(w http.ResponseWriter, r *http.Request, ws *workspace) {
+	var req fabric.CreateItemRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	for _, it := range ws.items {
+		if it.Type == req.Type && strings.EqualFold(it.DisplayName, req.DisplayName) {
+			writeFabricError(w, http.StatusConflict, "ItemDisplayNameAlreadyInUse", "Requested item display name is already in use")
+			return
+		}
+	}
+	it := &item{Item: fabric.Item{
+		Id:          s.newId(),
+		DisplayName: req.DisplayName,
+		Description: req.Description,
+		Type:        req.Type,
+		WorkspaceId: ws.Id,
+	}}
+	if req.Definition != nil {
+		it.definition = *req.Definition
+	}
+	ws.items = append(ws.items, it)
+	ws.recordChange(it.Item, fabric.ChangeAdded)
+	writeJSON(w, http.StatusCreated, it.Item)
+}
+
+func (s *Server) getItem(w http.ResponseWriter, r *http.Request, ws *workspace, it *item) {
+	writeJSON(w, http.StatusOK, it.Item)
+}
+
+func (s *Server) updateItem(w http.ResponseWriter, r *http.Request, ws *workspace, it *item) {
+	var req fabric.UpdateItemRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.DisplayName != "" {
+		for _, other := range ws.items {
+			if other != it && other.Type == it.Type && strings.EqualFold(other.DisplayName, req.DisplayName) {
+				writeFabricError(w, http.StatusConflict, "ItemDisplayNameAlreadyInUse", "Requested item display name is already in use")
+				return
+			}
+		}
+		it.DisplayName = req.DisplayName
+	}
+	if req.Description != "" {
+		it.Description = req.Description
+	}
+	ws.recordChange(it.Item, fabric.ChangeModified)
+	writeJSON(w, http.StatusOK, it.Item)
+}
+
+func (s *Server) deleteItem(w http.ResponseWriter, r *http.Request, ws *workspace, it *item) {
+	for i, v := range ws.items {
+		if v == it {
+			ws.items = append(ws.items[:i], ws.items[i+1:]...)
+			break
+		}
+	}
+	ws.recordChange(it.Item, fabric.ChangeDeleted)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getItemDefinition(w http.ResponseWriter, r *http.Request, ws *workspace, it *item) {
+	writeJSON(w, http.StatusOK, fabric.ItemDefinitionResponse{Definition: it.definition})
+}
+
+func (s *Server) updateItemDefinition(w http.ResponseWriter, r *http.Request, ws *workspace, it *item) {
+	var req fabric.ItemDefinitionResponse
+	if !decode(w, r, &req) {
+		return
+	}
+	it.definition = req.Definition
+	ws.recordChange(it.Item, fabric.ChangeModified)
+	w.WriteHeader(http.StatusOK)
+}
+
+// writePage responds with the page of all selected by the continuationToken query parameter.
+func writePage[T any](w http.ResponseWriter, r *http.Request, pageSize int, all []T) {
+	start, _ := strconv.Atoi(r.URL.Query().Get("continuationToken"))
+	start = min(start, len(all))
+	end := len(all)
+	if pageSize > 0 && start+pageSize < end {
+		end = start + pageSize
+	}
+	page := fabric.Page[T]{Value: append([]T{}, all[start:end]...)}
+	if end < len(all) {
+		page.ContinuationToken = strconv.Itoa(end)
+	}
+	writeJSON(w, http.StatusOK, page)
+}
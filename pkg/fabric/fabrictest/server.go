@@ -0,0 +1,211 @@
+// Package fabrictest provides an in-memory fake of the Fabric REST API and the Azure DevOps git
+// refs API, served over httptest, for testing code built on fabricant without network access.
+// Git content is not modelled: updating a workspace from git copies the items of another
+// workspace on the same repository at the target commit. Items created, changed or deleted
+// through the API in a workspace connected to git are reported by its git status until they
+// are committed.
+//
+// A typical test seeds a repository and a parent workspace, then points the clients at the
+// server:
+//
+//	srv := fabrictest.NewServer()
+//	defer srv.Close()
+//	srv.AddBranch("org", "project", "repo", "main", "c0ffee")
+//	parent := srv.AddWorkspace(fabric.Workspace{DisplayName: "Dev"})
+//	srv.ConnectGit(parent.Id, fabric.GitProviderDetails{...})
+//	res, err := workflow.CreateFeatureEnvironment(ctx, workflow.Options{
+//		Fabric: srv.FabricClient(),
+//		DevOps: srv.DevOpsClient(),
+//		...
+//	})
+package fabrictest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/amaliebjorgen/fabricant/pkg/auth"
+	"github.com/amaliebjorgen/fabricant/pkg/devops"
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+)
+
+// Token is the bearer token the clients returned by FabricClient and DevOpsClient send.
+const Token = "fabrictest-token"
+
+// Server is a fake Fabric and Azure DevOps API. The exported fields may be changed between
+// requests; all other state is accessed through methods, which are safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	// PageSize limits the number of workspaces per list page. Zero returns a single page.
+	PageSize int
+	// OperationPolls is the number of status polls a long-running operation reports Running
+	// before it completes. Each poll makes WaitForOperation sleep for its poll interval.
+	OperationPolls int
+	// RetryAfter is the Retry-After, in seconds, sent while an operation runs. Zero omits the
+	// header, so clients poll at their own interval.
+	RetryAfter int
+
+	mu         sync.Mutex
+	nextId     int
+	workspaces []*workspace
+	repos      map[string]*repo
+	operations map[string]*operation
+	faults     []*Fault
+	requests   []string
+}
+
+type workspace struct {
+	fabric.Workspace
+	git         *fabric.GitProviderDetails
+	credentials fabric.GitCredentials
+	head        string
+	items       []*item
+	// changes are the uncommitted item changes reported by the git status.
+	changes []*fabric.ItemChange
+}
+
+type repo struct {
+	branches     map[string]string
+	pullRequests []devops.CreatePullRequestRequest
+}
+
+type operation struct {
+	polls  int
+	err    *fabric.ErrorResponse
+	result interface{}
+	done   func()
+}
+
+// Fault makes matching requests fail.
+type Fault struct {
+	// Method and Path select the requests to fail. Path is a path.Match pattern against the
+	// request path, e.g. "/v1/workspaces/*/git/updateFromGit". An empty Method matches any.
+	Method string
+	Path   string
+
+	// Status and ErrorCode describe the error response. Status defaults to 500.
+	Status    int
+	ErrorCode string
+	Message   string
+
+	// Operation fails the long-running operation a matching request starts, rather than the
+	// request itself. Status is ignored.
+	Operation bool
+
+	// Times limits how many requests fail. Zero fails every matching request.
+	Times int
+
+	used int
+}
+
+// NewServer starts a server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		repos:      map[string]*repo{},
+		operations: map[string]*operation{},
+	}
+	mux := http.NewServeMux()
+	s.registerFabric(mux)
+	s.registerDevOps(mux)
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// FabricURL is the base URL to configure fabric clients with.
+func (s *Server) FabricURL() string {
+	return s.URL + "/v1"
+}
+
+// DevOpsURL is the collection URL template to configure devops clients with.
+func (s *Server) DevOpsURL() string {
+	return s.URL + "/devops/{organization}"
+}
+
+// FabricClient returns a client for the fake Fabric API. It does not retry.
+func (s *Server) FabricClient() *fabric.Client {
+	return fabric.NewClient(auth.StaticToken(Token), fabric.WithBaseURL(s.FabricURL()), fabric.WithHTTPClient(s.Client()))
+}
+
+// DevOpsClient returns a client for the fake Azure DevOps API. It does not retry.
+func (s *Server) DevOpsClient() *devops.Client {
+	return devops.NewClient(auth.StaticToken(Token), devops.WithBaseURL(s.DevOpsURL()), devops.WithHTTPClient(s.Client()))
+}
+
+// Fail registers a fault. Faults are checked in the order they were added.
+func (s *Server) Fail(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// Requests returns "METHOD /path" for every request received so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// middleware logs requests, checks the bearer token and applies request faults. It holds
+// the server lock for the duration of the request.
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+		if r.Header.Get("Authorization") != "Bearer "+Token {
+			writeFabricError(w, http.StatusUnauthorized, "TokenExpired", "Access token is missing or invalid")
+			return
+		}
+		if f := s.matchFault(r, false); f != nil {
+			status := f.Status
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			if strings.HasPrefix(r.URL.Path, "/devops/") {
+				writeDevOpsError(w, status, f.ErrorCode, f.Message)
+			} else {
+				writeFabricError(w, status, f.ErrorCode, f.Message)
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// matchFault returns the first active fault matching r and counts it. Called with s.mu held.
+func (s *Server) matchFault(r *http.Request, operation bool) *Fault {
+	for _, f := range s.faults {
+		if f.Operation != operation || (f.Times > 0 && f.used >= f.Times) {
+			continue
+		}
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if ok, _ := path.Match(f.Path, r.URL.Path); !ok {
+			continue
+		}
+		f.used++
+		return f
+	}
+	return nil
+}
+
+func (s *Server) newId() string {
+	s.nextId++
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", s.nextId)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if v != nil {
+		json.NewEncoder(w).Encode(v)
+	}
+}
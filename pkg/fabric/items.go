@@ -0,0 +1,180 @@
+package fabric
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"iter"
+	"net/http"
+
+	"github.com/amaliebjorgen/fabricant/pkg/transport"
+)
+
+// Item types fabricant handles specially.
+const (
+	ItemTypeLakehouse          = "Lakehouse"
+	ItemTypeNotebook           = "Notebook"
+	ItemTypeDataPipeline       = "DataPipeline"
+	ItemTypeSparkJobDefinition = "SparkJobDefinition"
+	ItemTypeSemanticModel      = "SemanticModel"
+	ItemTypeReport             = "Report"
+)
+
+// Item is a Fabric item, such as a Lakehouse or Notebook.
+type Item struct {
+	Id          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type"`
+	WorkspaceId string `json:"workspaceId,omitempty"`
+}
+
+// Items iterates GET /workspaces/{workspaceId}/items across all pages. itemType filters the
+// items by type if not empty.
+func (c *Client) Items(ctx context.Context, workspaceId, itemType string) iter.Seq2[Item, error] {
+	path := "/workspaces/" + workspaceId + "/items"
+	if itemType != "" {
+		path = withQuery(path, "type", itemType)
+	}
+	return listPaged[Item](ctx, c, path)
+}
+
+// ListItems calls GET /workspaces/{workspaceId}/items and returns every page.
+func (c *Client) ListItems(ctx context.Context, workspaceId, itemType string) ([]Item, error) {
+	return collect(c.Items(ctx, workspaceId, itemType))
+}
+
+// GetItem calls GET /workspaces/{workspaceId}/items/{itemId}
+func (c *Client) GetItem(ctx context.Context, workspaceId, itemId string) (*Item, error) {
+	var item Item
+	if _, err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf("/workspaces/%s/items/%s", workspaceId, itemId), nil, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// CreateItemRequest is the payload for creating an item.
+type CreateItemRequest struct {
+	DisplayName string          `json:"displayName"`
+	Type        string          `json:"type"`
+	Description string          `json:"description,omitempty"`
+	Definition  *ItemDefinition `json:"definition,omitempty"`
+}
+
+// CreateItem calls POST /workspaces/{workspaceId}/items, waiting for the operation if the
+// creation is long-running.
+func (c *Client) CreateItem(ctx context.Context, workspaceId string, req CreateItemRequest) (*Item, error) {
+	var item Item
+	if err := c.doLongRunningRequest(ctx, http.MethodPost, "/workspaces/"+workspaceId+"/items", req, &item, nil); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// UpdateItemRequest changes an item's properties. Empty fields are left unchanged.
+type UpdateItemRequest struct {
+	DisplayName string `json:"displayName,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// UpdateItem calls PATCH /workspaces/{workspaceId}/items/{itemId}
+func (c *Client) UpdateItem(ctx context.Context, workspaceId, itemId string, req UpdateItemRequest) (*Item, error) {
+	var item Item
+	if _, err := c.doRequest(ctx, http.MethodPatch, fmt.Sprintf("/workspaces/%s/items/%s", workspaceId, itemId), req, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// DeleteItem calls DELETE /workspaces/{workspaceId}/items/{itemId}
+func (c *Client) DeleteItem(ctx context.Context, workspaceId, itemId string) error {
+	_, err := c.doRequest(ctx, http.MethodDelete, fmt.Sprintf("/workspaces/%s/items/%s", workspaceId, itemId), nil, nil)
+	return err
+}
+
+// Payload types of definition parts.
+const PayloadTypeInlineBase64 = "InlineBase64"
+
+// ItemDefinition is the content of an item as a set of files.
+type ItemDefinition struct {
+	Format string               `json:"format,omitempty"`
+	Parts  []ItemDefinitionPart `json:"parts"`
+}
+
+// ItemDefinitionPart is one file of an item definition.
+type ItemDefinitionPart struct {
+	Path        string `json:"path"`
+	Payload     string `json:"payload"`
+	PayloadType string `json:"payloadType"`
+}
+
+// NewItemDefinitionPart returns a part holding content.
+func NewItemDefinitionPart(path string, content []byte) ItemDefinitionPart {
+	p := ItemDefinitionPart{Path: path}
+	p.SetContent(content)
+	return p
+}
+
+// Part returns the part with the given path, or nil.
+func (d *ItemDefinition) Part(path string) *ItemDefinitionPart {
+	for i := range d.Parts {
+		if d.Parts[i].Path == path {
+			return &d.Parts[i]
+		}
+	}
+	return nil
+}
+
+// Files decodes every part, keyed by path.
+func (d *ItemDefinition) Files() (map[string][]byte, error) {
+	files := make(map[string][]byte, len(d.Parts))
+	for _, p := range d.Parts {
+		b, err := p.Decode()
+		if err != nil {
+			return nil, err
+		}
+		files[p.Path] = b
+	}
+	return files, nil
+}
+
+// Decode returns the part's content.
+func (p ItemDefinitionPart) Decode() ([]byte, error) {
+	if p.PayloadType != "" && p.PayloadType != PayloadTypeInlineBase64 {
+		return nil, fmt.Errorf("unsupported payload type %q for %s", p.PayloadType, p.Path)
+	}
+	return base64.StdEncoding.DecodeString(p.Payload)
+}
+
+// SetContent replaces the part's content.
+func (p *ItemDefinitionPart) SetContent(b []byte) {
+	p.Payload = base64.StdEncoding.EncodeToString(b)
+	p.PayloadType = PayloadTypeInlineBase64
+}
+
+// ItemDefinitionResponse wraps an item definition.
+type ItemDefinitionResponse struct {
+	Definition ItemDefinition `json:"definition"`
+}
+
+// GetItemDefinition calls POST /workspaces/{workspaceId}/items/{itemId}/getDefinition. format
+// selects a definition format where the item type has several, e.g. "ipynb" for notebooks.
+func (c *Client) GetItemDefinition(ctx context.Context, workspaceId, itemId, format string) (*ItemDefinition, error) {
+	path := fmt.Sprintf("/workspaces/%s/items/%s/getDefinition", workspaceId, itemId)
+	if format != "" {
+		path = withQuery(path, "format", format)
+	}
+	var resp ItemDefinitionResponse
+	// getDefinition only reads, so it is safe to retry despite being a POST.
+	if err := c.doLongRunningRequest(transport.WithIdempotent(ctx), http.MethodPost, path, nil, &resp, nil); err != nil {
+		return nil, err
+	}
+	return &resp.Definition, nil
+}
+
+// UpdateItemDefinition calls POST /workspaces/{workspaceId}/items/{itemId}/updateDefinition,
+// waiting for the operation if the update is long-running.
+func (c *Client) UpdateItemDefinition(ctx context.Context, workspaceId, itemId string, def ItemDefinition) error {
+	path := fmt.Sprintf("/workspaces/%s/items/%s/updateDefinition", workspaceId, itemId)
+	return c.doLongRunningRequest(ctx, http.MethodPost, path, ItemDefinitionResponse{Definition: def}, nil, nil)
+}
@@ -0,0 +1,173 @@
+package fabric
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operation states reported by the Fabric Operations API.
+const (
+	OperationNotStarted = "NotStarted"
+	OperationRunning    = "Running"
+	OperationSucceeded  = "Succeeded"
+	OperationFailed     = "Failed"
+	OperationUndefined  = "Undefined"
+)
+
+// DefaultPollInterval is used between status polls when the service sends no Retry-After.
+const DefaultPollInterval = 2 * time.Second
+
+// WaitOptions configures WaitForOperation.
+type WaitOptions struct {
+	// PollInterval is the delay between polls when the service does not send Retry-After.
+	// Defaults to DefaultPollInterval.
+	PollInterval time.Duration
+	// Timeout bounds the total wait. Zero means wait until ctx is done.
+	Timeout time.Duration
+	// OnProgress, if set, is called with every status that is not yet terminal.
+	OnProgress func(*OperationStatus)
+	// Result fetches the operation result even when the final status has no Location header.
+	// Operations that produce a result expose it at /operations/{operationId}/result either way.
+	Result bool
+}
+
+// WaitForOperation polls GET /operations/{operationId} until the operation succeeds or fails,
+// honoring Retry-After and ctx. On success it returns the payload of
+// GET /operations/{operationId}/result if the service points to it with a Location header or
+// opts.Result is set, or nil if the operation has no result.
+func (c *Client) WaitForOperation(ctx context.Context, operationId string, opts *WaitOptions) (json.RawMessage, error) {
+	if opts == nil {
+		opts = &WaitOptions{}
+	}
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	path := fmt.Sprintf("/operations/%s", operationId)
+	for {
+		var status OperationStatus
+		resp, err := c.doRequest(ctx, http.MethodGet, path, nil, &status)
+		if err != nil {
+			return nil, fmt.Errorf("checking operation %s: %w", operationId, err)
+		}
+
+		switch status.Status {
+		case OperationSucceeded:
+			hasLocation := resp.Header.Get("Location") != ""
+			if !hasLocation && !opts.Result {
+				return nil, nil
+			}
+			result, err := c.GetOperationResult(ctx, operationId)
+			if !hasLocation && IsErrorCode(err, ErrorCodeOperationHasNoResult) {
+				return nil, nil
+			}
+			return result, err
+		case OperationFailed, OperationUndefined:
+			opErr := &OperationError{OperationId: operationId}
+			if status.Error != nil {
+				opErr.ErrorResponse = *status.Error
+			}
+			return nil, opErr
+		}
+
+		if opts.OnProgress != nil {
+			opts.OnProgress(&status)
+		}
+		if err := sleepCtx(ctx, retryAfter(resp, interval)); err != nil {
+			return nil, fmt.Errorf("waiting for operation %s: %w", operationId, err)
+		}
+	}
+}
+
+// GetOperationResult calls GET /operations/{operationId}/result
+func (c *Client) GetOperationResult(ctx context.Context, operationId string) (json.RawMessage, error) {
+	var raw json.RawMessage
+	_, err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf("/operations/%s/result", operationId), nil, &raw)
+	if err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// doLongRunningRequest performs a request that may be accepted as a long-running operation.
+// A 200/201 response is decoded into out directly. A 202 response is waited for with
+// WaitForOperation and the operation result, if any, is decoded into out.
+func (c *Client) doLongRunningRequest(ctx context.Context, method, path string, body interface{}, out interface{}, opts *WaitOptions) error {
+	resp, err := c.doRequest(ctx, method, path, body, out)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return nil
+	}
+
+	operationId := operationIdFromResponse(resp)
+	if operationId == "" {
+		return fmt.Errorf("%s %s was accepted without an operation id", method, path)
+	}
+	var o WaitOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.PollInterval <= 0 {
+		// The first Retry-After tells us how soon the service expects to be asked again.
+		o.PollInterval = retryAfter(resp, DefaultPollInterval)
+	}
+	o.Result = out != nil
+
+	result, err := c.WaitForOperation(ctx, operationId, &o)
+	if err != nil {
+		return err
+	}
+	if out != nil && len(result) > 0 {
+		return json.Unmarshal(result, out)
+	}
+	return nil
+}
+
+// operationIdFromResponse extracts the operation id from the x-ms-operation-id header, falling
+// back to the last segment of the Location header.
+func operationIdFromResponse(resp *http.Response) string {
+	if id := resp.Header.Get("x-ms-operation-id"); id != "" {
+		return id
+	}
+	loc := strings.TrimSuffix(resp.Header.Get("Location"), "/")
+	if i := strings.Index(loc, "/operations/"); i >= 0 {
+		return loc[i+len("/operations/"):]
+	}
+	return ""
+}
+
+// retryAfter returns the delay requested by the Retry-After header in seconds, or def.
+func retryAfter(resp *http.Response, def time.Duration) time.Duration {
+	if resp == nil {
+		return def
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return def
+}
+
+// sleepCtx waits for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
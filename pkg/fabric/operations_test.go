@@ -0,0 +1,86 @@
+package fabric_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/amaliebjorgen/fabricant/pkg/fabric/fabrictest"
+)
+
+// startUpdate connects a workspace to a branch, moves the branch and starts updating the
+// workspace from it, returning the workspace id and the operation id.
+func startUpdate(t *testing.T, srv *fabrictest.Server, c *fabric.Client) (string, string) {
+	t.Helper()
+	git := fabric.GitProviderDetails{
+		GitProviderType:  fabric.GitProviderAzureDevOps,
+		OrganizationName: "contoso",
+		ProjectName:      "data",
+		RepositoryName:   "fabric",
+		BranchName:       "main",
+	}
+	srv.AddBranch(git.OrganizationName, git.ProjectName, git.RepositoryName, git.BranchName, "c1")
+	ws := srv.AddWorkspace(fabric.Workspace{DisplayName: "Dev"})
+	srv.ConnectGit(ws.Id, git)
+	srv.AddBranch(git.OrganizationName, git.ProjectName, git.RepositoryName, git.BranchName, "c2")
+
+	opId, err := c.UpdateWorkspaceFromGit(context.Background(), ws.Id, "c1", "c2")
+	if err != nil {
+		t.Fatalf("UpdateWorkspaceFromGit: %v", err)
+	}
+	if opId == "" {
+		t.Fatal("UpdateWorkspaceFromGit returned no operation id")
+	}
+	return ws.Id, opId
+}
+
+func TestWaitForOperation(t *testing.T) {
+	srv := fabrictest.NewServer()
+	defer srv.Close()
+	srv.OperationPolls = 3
+	c := srv.FabricClient()
+	wsId, opId := startUpdate(t, srv, c)
+
+	var progress []int
+	start := time.Now()
+	result, err := c.WaitForOperation(context.Background(), opId, &fabric.WaitOptions{
+		PollInterval: time.Millisecond,
+		OnProgress: func(status *fabric.OperationStatus) {
+			progress = append(progress, status.PercentComplete)
+		},
+	})
+	if err != nil {
+		t.Fatalf("WaitForOperation: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("WaitForOperation took %s, want it to follow the poll interval", elapsed)
+	}
+	if result != nil {
+		t.Errorf("result = %s, want none", result)
+	}
+	if len(progress) != srv.OperationPolls {
+		t.Errorf("got %d progress reports, want %d", len(progress), srv.OperationPolls)
+	}
+
+	status, err := c.GetGitStatus(context.Background(), wsId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.WorkspaceHead != "c2" {
+		t.Errorf("workspace head = %q after the update, want c2", status.WorkspaceHead)
+	}
+}
+
+func TestWaitForOperationFailure(t *testing.T) {
+	srv := fabrictest.NewServer()
+	defer srv.Close()
+	srv.Fail(fabrictest.Fault{Path: "/v1/workspaces/*/git/updateFromGit", Operation: true, ErrorCode: "GitSyncFailed", Message: "sync failed"})
+	c := srv.FabricClient()
+	_, opId := startUpdate(t, srv, c)
+
+	_, err := c.WaitForOperation(context.Background(), opId, &fabric.WaitOptions{PollInterval: time.Millisecond})
+	if !fabric.IsErrorCode(err, "GitSyncFailed") {
+		t.Fatalf("WaitForOperation error = %v, want GitSyncFailed", err)
+	}
+}
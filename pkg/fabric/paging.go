@@ -0,0 +1,62 @@
+package fabric
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Page is the envelope of paged Fabric list responses.
+type Page[T any] struct {
+	Value             []T    `json:"value"`
+	ContinuationToken string `json:"continuationToken,omitempty"`
+	ContinuationUri   string `json:"continuationUri,omitempty"`
+}
+
+// listPaged iterates every item of a paged list endpoint, following continuation tokens. The
+// iteration stops after yielding the first error.
+func listPaged[T any](ctx context.Context, c *Client, path string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		next := path
+		for {
+			var p Page[T]
+			if _, err := c.doRequest(ctx, http.MethodGet, next, nil, &p); err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, v := range p.Value {
+				if !yield(v, nil) {
+					return
+				}
+			}
+			if p.ContinuationToken == "" {
+				return
+			}
+			next = withQuery(path, "continuationToken", p.ContinuationToken)
+		}
+	}
+}
+
+// collect drains a paged iterator into a slice.
+func collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var out []T
+	for v, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// withQuery appends an escaped query parameter to path.
+func withQuery(path, key, value string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + key + "=" + url.QueryEscape(value)
+}
@@ -0,0 +1,17 @@
+package github
+
+import (
+	"context"
+	"iter"
+)
+
+// API is the set of GitHub operations implemented by *Client, for substituting fakes.
+type API interface {
+	Branches(ctx context.Context, owner, repo string) iter.Seq2[Branch, error]
+	GetBranchSha(ctx context.Context, owner, repo, branchName string) (string, error)
+	CreateBranch(ctx context.Context, owner, repo, branchName, sha string) error
+	DeleteBranch(ctx context.Context, owner, repo, branchName string) error
+	CreatePullRequest(ctx context.Context, owner, repo string, req CreatePullRequestRequest) (*PullRequest, error)
+}
+
+var _ API = (*Client)(nil)
@@ -0,0 +1,151 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WithApp authenticates as a GitHub App installation. If installationId is 0 the installation
+// is looked up from the repository of each request.
+func WithApp(appId string, key *rsa.PrivateKey, installationId int64) Option {
+	return func(c *Client) {
+		c.app = &appAuth{
+			appId:          appId,
+			key:            key,
+			installationId: installationId,
+			tokens:         map[int64]installationToken{},
+			installations:  map[string]int64{},
+		}
+	}
+}
+
+// ParsePrivateKey parses a GitHub App private key in PKCS#1 or PKCS#8 PEM form.
+func ParsePrivateKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("github app key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing github app key: %w", err)
+	}
+	key, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("github app key is not an RSA key")
+	}
+	return key, nil
+}
+
+// appAuth mints installation tokens for a GitHub App and caches them until shortly before
+// they expire.
+type appAuth struct {
+	appId          string
+	key            *rsa.PrivateKey
+	installationId int64
+
+	mu            sync.Mutex
+	tokens        map[int64]installationToken
+	installations map[string]int64
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (a *appAuth) installationToken(ctx context.Context, c *Client, owner, repo string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	id := a.installationId
+	if id == 0 {
+		key := owner + "/" + repo
+		id = a.installations[key]
+		if id == 0 {
+			var inst struct {
+				Id int64 `json:"id"`
+			}
+			path := fmt.Sprintf("/repos/%s/%s/installation", url.PathEscape(owner), url.PathEscape(repo))
+			if err := a.appRequest(ctx, c, http.MethodGet, path, &inst); err != nil {
+				return "", fmt.Errorf("finding app installation for %s: %w", key, err)
+			}
+			id = inst.Id
+			a.installations[key] = id
+		}
+	}
+
+	if tok, ok := a.tokens[id]; ok && time.Now().Before(tok.ExpiresAt.Add(-time.Minute)) {
+		return tok.Token, nil
+	}
+	var tok installationToken
+	if err := a.appRequest(ctx, c, http.MethodPost, fmt.Sprintf("/app/installations/%d/access_tokens", id), &tok); err != nil {
+		return "", err
+	}
+	a.tokens[id] = tok
+	return tok.Token, nil
+}
+
+// appRequest calls an endpoint that authenticates as the app itself, using a JWT.
+func (a *appAuth) appRequest(ctx context.Context, c *Client, method, path string, out interface{}) error {
+	jwt, err := a.jwt()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	_, err = c.send(req, false, out)
+	return err
+}
+
+// jwt returns an RS256-signed app JWT, backdated a minute to allow for clock drift.
+func (a *appAuth) jwt() (string, error) {
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.appId,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing github app jwt: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// ParseInstallationId parses an installation id, treating "" as 0.
+func ParseInstallationId(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid github app installation id %q", s)
+	}
+	return id, nil
+}
@@ -0,0 +1,173 @@
+// Package github is a REST client for the parts of the GitHub API fabricant needs to manage
+// feature branches.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/amaliebjorgen/fabricant/pkg/transport"
+)
+
+// DefaultBaseURL is the github.com API endpoint.
+const DefaultBaseURL = "https://api.github.com"
+
+// Client is the REST client for GitHub APIs.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+	app        *appAuth
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests. By default requests go through a
+// transport.RetryTransport with transport.DefaultRetryPolicy.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithBaseURL sets the API endpoint, e.g. "https://github.contoso.com/api/v3" for GitHub
+// Enterprise Server.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithToken authenticates with a personal access token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// NewClient creates a new GitHub API client.
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient: &http.Client{Transport: transport.NewRetryTransport(nil, transport.DefaultRetryPolicy)},
+		baseURL:    DefaultBaseURL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// doRequest performs a request against the GitHub REST API. owner and repo select the GitHub
+// App installation when app authentication is used.
+func (c *Client) doRequest(ctx context.Context, owner, repo, method, path string, body interface{}, out interface{}) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewBuffer(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	token := c.token
+	if c.app != nil {
+		token, err = c.app.installationToken(ctx, c, owner, repo)
+		if err != nil {
+			return nil, fmt.Errorf("getting github app token: %w", err)
+		}
+	}
+	if token == "" {
+		return nil, errors.New("no github credentials: configure a token or a GitHub App")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return c.send(req, body != nil, out)
+}
+
+// send executes req and decodes the response, shared by token and app-JWT requests.
+func (c *Client) send(req *http.Request, hasBody bool, out interface{}) (*http.Response, error) {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if hasBody {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		b, _ := io.ReadAll(resp.Body)
+		return resp, newAPIError(resp, b)
+	}
+
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+// GitRef represents a git reference.
+type GitRef struct {
+	Ref    string `json:"ref"`
+	Object struct {
+		Sha  string `json:"sha"`
+		Type string `json:"type"`
+	} `json:"object"`
+}
+
+func refPath(owner, repo, ref string) string {
+	return fmt.Sprintf("/repos/%s/%s/git/ref/%s", url.PathEscape(owner), url.PathEscape(repo), ref)
+}
+
+// GetBranchSha returns the commit sha the branch points to.
+func (c *Client) GetBranchSha(ctx context.Context, owner, repo, branchName string) (string, error) {
+	var ref GitRef
+	_, err := c.doRequest(ctx, owner, repo, http.MethodGet, refPath(owner, repo, "heads/"+shortBranchName(branchName)), nil, &ref)
+	if err != nil {
+		if IsNotFound(err) {
+			return "", fmt.Errorf("%w: %s in repo %s/%s", ErrBranchNotFound, branchName, owner, repo)
+		}
+		return "", err
+	}
+	return ref.Object.Sha, nil
+}
+
+// CreateBranch creates a new branch pointing at sha.
+func (c *Client) CreateBranch(ctx context.Context, owner, repo, branchName, sha string) error {
+	path := fmt.Sprintf("/repos/%s/%s/git/refs", url.PathEscape(owner), url.PathEscape(repo))
+	req := map[string]string{
+		"ref": "refs/heads/" + shortBranchName(branchName),
+		"sha": sha,
+	}
+	_, err := c.doRequest(ctx, owner, repo, http.MethodPost, path, req, nil)
+	return err
+}
+
+// DeleteBranch deletes a branch. A missing branch is reported as ErrBranchNotFound.
+func (c *Client) DeleteBranch(ctx context.Context, owner, repo, branchName string) error {
+	path := fmt.Sprintf("/repos/%s/%s/git/refs/heads/%s", url.PathEscape(owner), url.PathEscape(repo), shortBranchName(branchName))
+	_, err := c.doRequest(ctx, owner, repo, http.MethodDelete, path, nil, nil)
+	return err
+}
+
+func shortBranchName(branchName string) string {
+	return strings.TrimPrefix(branchName, "refs/heads/")
+}
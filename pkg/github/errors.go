@@ -0,0 +1,57 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrBranchAlreadyExists is matched by errors.Is when creating a branch that already exists.
+var ErrBranchAlreadyExists = errors.New("branch already exists")
+
+// ErrBranchNotFound is matched by errors.Is when resolving or deleting a branch that does not exist.
+var ErrBranchNotFound = errors.New("branch not found")
+
+// APIError is returned for GitHub responses with a 4xx or 5xx status.
+type APIError struct {
+	StatusCode       int    `json:"-"`
+	Message          string `json:"message"`
+	DocumentationURL string `json:"documentation_url,omitempty"`
+	// RequestId is the X-GitHub-Request-Id header.
+	RequestId string `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("github API error %d: %s", e.StatusCode, e.Message)
+}
+
+// Is reports GitHub's "Reference already exists" validation error as ErrBranchAlreadyExists,
+// and a 404 or "Reference does not exist" error as ErrBranchNotFound.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBranchAlreadyExists:
+		return e.StatusCode == http.StatusUnprocessableEntity && strings.Contains(e.Message, "Reference already exists")
+	case ErrBranchNotFound:
+		return e.StatusCode == http.StatusNotFound ||
+			e.StatusCode == http.StatusUnprocessableEntity && strings.Contains(e.Message, "Reference does not exist")
+	}
+	return false
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{}
+	if err := json.Unmarshal(body, e); err != nil || e.Message == "" {
+		e.Message = string(body)
+	}
+	e.StatusCode = resp.StatusCode
+	e.RequestId = resp.Header.Get("X-GitHub-Request-Id")
+	return e
+}
+
+// IsNotFound reports whether err is a GitHub 404.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
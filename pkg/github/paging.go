@@ -0,0 +1,42 @@
+package github
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"strings"
+)
+
+// listPaged iterates every item of a list endpoint, following the rel="next" links of the
+// Link header. The iteration stops after yielding the first error.
+func listPaged[T any](ctx context.Context, c *Client, owner, repo, path string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		next := path
+		for next != "" {
+			var page []T
+			resp, err := c.doRequest(ctx, owner, repo, http.MethodGet, next, nil, &page)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, v := range page {
+				if !yield(v, nil) {
+					return
+				}
+			}
+			next = strings.TrimPrefix(nextLink(resp.Header.Get("Link")), c.baseURL)
+		}
+	}
+}
+
+// nextLink returns the rel="next" URL of a Link header, or "" on the last page.
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if ok && strings.Contains(params, `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return ""
+}
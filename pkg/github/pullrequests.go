@@ -0,0 +1,50 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+)
+
+// Branch is a branch as returned by the branches list.
+type Branch struct {
+	Name   string `json:"name"`
+	Commit struct {
+		Sha string `json:"sha"`
+	} `json:"commit"`
+}
+
+// Branches iterates the branches of a repository.
+func (c *Client) Branches(ctx context.Context, owner, repo string) iter.Seq2[Branch, error] {
+	path := fmt.Sprintf("/repos/%s/%s/branches?per_page=100", url.PathEscape(owner), url.PathEscape(repo))
+	return listPaged[Branch](ctx, c, owner, repo, path)
+}
+
+// CreatePullRequestRequest opens a pull request from Head into Base.
+type CreatePullRequestRequest struct {
+	Title string `json:"title"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+	Body  string `json:"body,omitempty"`
+	Draft bool   `json:"draft,omitempty"`
+}
+
+// PullRequest is a created pull request.
+type PullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+// CreatePullRequest opens a pull request.
+func (c *Client) CreatePullRequest(ctx context.Context, owner, repo string, req CreatePullRequestRequest) (*PullRequest, error) {
+	req.Head = shortBranchName(req.Head)
+	req.Base = shortBranchName(req.Base)
+	path := fmt.Sprintf("/repos/%s/%s/pulls", url.PathEscape(owner), url.PathEscape(repo))
+	var pr PullRequest
+	if _, err := c.doRequest(ctx, owner, repo, http.MethodPost, path, req, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
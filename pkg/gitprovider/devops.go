@@ -0,0 +1,59 @@
+package gitprovider
+
+import (
+	"context"
+	"iter"
+	"strings"
+
+	"github.com/amaliebjorgen/fabricant/pkg/devops"
+)
+
+// AzureDevOps is the Provider for Azure DevOps repositories.
+type AzureDevOps struct {
+	Client       devops.API
+	Organization string
+	Project      string
+	Repository   string
+}
+
+// ResolveBranch returns the object id of the branch ref.
+func (p *AzureDevOps) ResolveBranch(ctx context.Context, branch string) (string, error) {
+	return p.Client.GetBranchObjectId(ctx, p.Organization, p.Project, p.Repository, branch)
+}
+
+// CreateBranch pushes a new ref for branch at commitId.
+func (p *AzureDevOps) CreateBranch(ctx context.Context, branch, commitId string) error {
+	return p.Client.CreateBranch(ctx, p.Organization, p.Project, p.Repository, branch, commitId)
+}
+
+// DeleteBranch removes the branch ref.
+func (p *AzureDevOps) DeleteBranch(ctx context.Context, branch string) error {
+	return p.Client.DeleteBranch(ctx, p.Organization, p.Project, p.Repository, branch)
+}
+
+// ListBranches iterates the refs under refs/heads/.
+func (p *AzureDevOps) ListBranches(ctx context.Context) iter.Seq2[Branch, error] {
+	return func(yield func(Branch, error) bool) {
+		for ref, err := range p.Client.Refs(ctx, p.Organization, p.Project, p.Repository, "heads/") {
+			b := Branch{Name: strings.TrimPrefix(ref.Name, "refs/heads/"), CommitId: ref.ObjectId}
+			if !yield(b, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// CreatePullRequest opens an Azure DevOps pull request.
+func (p *AzureDevOps) CreatePullRequest(ctx context.Context, req PullRequestRequest) (*PullRequest, error) {
+	pr, err := p.Client.CreatePullRequest(ctx, p.Organization, p.Project, p.Repository, devops.CreatePullRequestRequest{
+		SourceRefName: req.SourceBranch,
+		TargetRefName: req.TargetBranch,
+		Title:         req.Title,
+		Description:   req.Description,
+		IsDraft:       req.Draft,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &PullRequest{Id: pr.PullRequestId, WebURL: pr.WebURL()}, nil
+}
@@ -0,0 +1,56 @@
+package gitprovider
+
+import (
+	"context"
+	"iter"
+
+	"github.com/amaliebjorgen/fabricant/pkg/github"
+)
+
+// GitHub is the Provider for GitHub repositories.
+type GitHub struct {
+	Client     github.API
+	Owner      string
+	Repository string
+}
+
+// ResolveBranch returns the SHA of the branch head.
+func (p *GitHub) ResolveBranch(ctx context.Context, branch string) (string, error) {
+	return p.Client.GetBranchSha(ctx, p.Owner, p.Repository, branch)
+}
+
+// CreateBranch creates branch pointing at the commitId SHA.
+func (p *GitHub) CreateBranch(ctx context.Context, branch, commitId string) error {
+	return p.Client.CreateBranch(ctx, p.Owner, p.Repository, branch, commitId)
+}
+
+// DeleteBranch deletes the branch.
+func (p *GitHub) DeleteBranch(ctx context.Context, branch string) error {
+	return p.Client.DeleteBranch(ctx, p.Owner, p.Repository, branch)
+}
+
+// ListBranches iterates the repository branches.
+func (p *GitHub) ListBranches(ctx context.Context) iter.Seq2[Branch, error] {
+	return func(yield func(Branch, error) bool) {
+		for b, err := range p.Client.Branches(ctx, p.Owner, p.Repository) {
+			if !yield(Branch{Name: b.Name, CommitId: b.Commit.Sha}, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// CreatePullRequest opens a GitHub pull request.
+func (p *GitHub) CreatePullRequest(ctx context.Context, req PullRequestRequest) (*PullRequest, error) {
+	pr, err := p.Client.CreatePullRequest(ctx, p.Owner, p.Repository, github.CreatePullRequestRequest{
+		Title: req.Title,
+		Head:  req.SourceBranch,
+		Base:  req.TargetBranch,
+		Body:  req.Description,
+		Draft: req.Draft,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &PullRequest{Id: pr.Number, WebURL: pr.HTMLURL}, nil
+}
@@ -0,0 +1,89 @@
+// Package gitprovider abstracts the branch and pull request operations of the git hosts that
+// Fabric workspaces can be connected to.
+package gitprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/amaliebjorgen/fabricant/pkg/devops"
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/amaliebjorgen/fabricant/pkg/github"
+)
+
+// Provider performs git operations against a single repository. Branch names may be given
+// with or without the refs/heads/ prefix.
+type Provider interface {
+	// ResolveBranch returns the commit id the branch points to.
+	ResolveBranch(ctx context.Context, branch string) (string, error)
+	// CreateBranch creates branch at commitId.
+	CreateBranch(ctx context.Context, branch, commitId string) error
+	// DeleteBranch deletes branch.
+	DeleteBranch(ctx context.Context, branch string) error
+	// ListBranches iterates the branches of the repository.
+	ListBranches(ctx context.Context) iter.Seq2[Branch, error]
+	// CreatePullRequest opens a pull request.
+	CreatePullRequest(ctx context.Context, req PullRequestRequest) (*PullRequest, error)
+}
+
+// IsBranchNotFound reports whether err from any provider says the branch does not exist.
+func IsBranchNotFound(err error) bool {
+	return errors.Is(err, devops.ErrBranchNotFound) || errors.Is(err, github.ErrBranchNotFound)
+}
+
+// IsBranchAlreadyExists reports whether err from any provider says the branch already exists.
+func IsBranchAlreadyExists(err error) bool {
+	return errors.Is(err, devops.ErrBranchAlreadyExists) || errors.Is(err, github.ErrBranchAlreadyExists)
+}
+
+// Branch is a branch and its head commit.
+type Branch struct {
+	Name     string
+	CommitId string
+}
+
+// PullRequestRequest describes a pull request from SourceBranch into TargetBranch.
+type PullRequestRequest struct {
+	SourceBranch string
+	TargetBranch string
+	Title        string
+	Description  string
+	Draft        bool
+}
+
+// PullRequest is a created pull request.
+type PullRequest struct {
+	Id     int
+	WebURL string
+}
+
+// Clients holds the provider API clients to choose from. Only the client for the repository's
+// provider needs to be set.
+type Clients struct {
+	DevOps devops.API
+	GitHub github.API
+}
+
+// ForRepository returns the provider for the repository a workspace is connected to, selected
+// by its GitProviderType.
+func ForRepository(git *fabric.GitProviderDetails, c Clients) (Provider, error) {
+	if git == nil {
+		return nil, fmt.Errorf("workspace does not have git integration")
+	}
+	switch git.GitProviderType {
+	case fabric.GitProviderAzureDevOps:
+		if c.DevOps == nil {
+			return nil, fmt.Errorf("a DevOps client is required for %s repositories", git.GitProviderType)
+		}
+		return &AzureDevOps{Client: c.DevOps, Organization: git.OrganizationName, Project: git.ProjectName, Repository: git.RepositoryName}, nil
+	case fabric.GitProviderGitHub:
+		if c.GitHub == nil {
+			return nil, fmt.Errorf("a GitHub client is required for %s repositories", git.GitProviderType)
+		}
+		return &GitHub{Client: c.GitHub, Owner: git.OwnerName, Repository: git.RepositoryName}, nil
+	default:
+		return nil, fmt.Errorf("unsupported git provider %q", git.GitProviderType)
+	}
+}
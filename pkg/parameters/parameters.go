@@ -0,0 +1,250 @@
+// Package parameters reads parameter.yml find-and-replace files and applies them to item
+// definitions deployed to a target environment.
+//
+// The format follows fabric-cicd's find_replace section:
+//
+//	find_replace:
+//	  - find_value: "dev-sql.database.windows.net"
+//	    replace_value:
+//	      feature: "feature-sql.database.windows.net"
+//	  - find_value: "5f2c1a9e-0d4b-4e8a-9c3f-6b7d8e9f0a1b"
+//	    replace_value:
+//	      feature: "$workspace.id"
+//	    item_type: DataPipeline
+//	    file_path: "*.json"
+//	  - find_value: 'Initial Catalog=\w+_dev'
+//	    is_regex: true
+//	    replace_value:
+//	      feature: "Initial Catalog=sales_feature"
+//
+// Rules apply only to environments listed in replace_value. A replacement that consists of a
+// variable such as $workspace.id or $items.Lakehouse.Bronze.id is resolved against the target
+// workspace; in regex rules, other $ references expand capture groups.
+package parameters
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultItemTypes are the item types rules without item_type apply to.
+var DefaultItemTypes = []string{
+	fabric.ItemTypeNotebook, fabric.ItemTypeDataPipeline, fabric.ItemTypeSparkJobDefinition,
+	fabric.ItemTypeSemanticModel, fabric.ItemTypeReport,
+}
+
+// File is the content of a parameter file.
+type File struct {
+	FindReplace []FindReplace `yaml:"find_replace"`
+}
+
+// FindReplace is a single find-and-replace rule.
+type FindReplace struct {
+	FindValue string `yaml:"find_value"`
+	// ReplaceValue maps environment names to replacements.
+	ReplaceValue map[string]string `yaml:"replace_value"`
+	IsRegex      bool              `yaml:"is_regex"`
+
+	// ItemType, ItemName and FilePath restrict the rule to items of the given types, items
+	// with the given display names, and definition files matching the given path.Match
+	// patterns. Each accepts a single value or a list.
+	ItemType StringList `yaml:"item_type"`
+	ItemName StringList `yaml:"item_name"`
+	FilePath StringList `yaml:"file_path"`
+}
+
+// StringList is a YAML value that may be written as a string or a list of strings.
+type StringList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (l *StringList) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*l = StringList{n.Value}
+		return nil
+	}
+	var v []string
+	if err := n.Decode(&v); err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+// Load reads and validates a parameter file.
+func Load(filename string) (*File, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading parameter file: %w", err)
+	}
+	f, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return f, nil
+}
+
+// Parse parses and validates a parameter file.
+func Parse(b []byte) (*File, error) {
+	var f File
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parsing parameter file: %w", err)
+	}
+	for i, r := range f.FindReplace {
+		if r.FindValue == "" {
+			return nil, fmt.Errorf("find_replace[%d]: find_value is required", i)
+		}
+		if r.IsRegex {
+			if _, err := regexp.Compile(r.FindValue); err != nil {
+				return nil, fmt.Errorf("find_replace[%d]: %w", i, err)
+			}
+		}
+		for _, p := range r.FilePath {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("find_replace[%d]: invalid file_path %q: %w", i, p, err)
+			}
+		}
+	}
+	return &f, nil
+}
+
+// Target describes the workspace definitions are deployed to.
+type Target struct {
+	Environment string
+	Workspace   fabric.Workspace
+	// Items are the workspace's items, for $items references.
+	Items []fabric.Item
+}
+
+// Replacer applies the rules of a File for one target.
+type Replacer struct {
+	rules []rule
+}
+
+type rule struct {
+	FindReplace
+	re      *regexp.Regexp
+	replace string
+}
+
+// ForTarget resolves the rules that apply to target's environment.
+func (f *File) ForTarget(target Target) (*Replacer, error) {
+	r := &Replacer{}
+	for i, fr := range f.FindReplace {
+		value, ok := fr.ReplaceValue[target.Environment]
+		if !ok {
+			continue
+		}
+		value, err := resolve(value, target)
+		if err != nil {
+			return nil, fmt.Errorf("find_replace[%d]: %w", i, err)
+		}
+		ru := rule{FindReplace: fr, replace: value}
+		if fr.IsRegex {
+			ru.re = regexp.MustCompile(fr.FindValue)
+		}
+		r.rules = append(r.rules, ru)
+	}
+	return r, nil
+}
+
+// resolve substitutes a replacement that is a variable reference.
+func resolve(value string, target Target) (string, error) {
+	ref, ok := strings.CutPrefix(value, "$")
+	if !ok {
+		return value, nil
+	}
+	switch ref {
+	case "workspace.id":
+		return target.Workspace.Id, nil
+	case "workspace.name":
+		return target.Workspace.DisplayName, nil
+	}
+	if rest, ok := strings.CutPrefix(ref, "items."); ok {
+		// $items.<type>.<name>.<attribute>; names may contain dots.
+		itemType, rest, _ := strings.Cut(rest, ".")
+		i := strings.LastIndex(rest, ".")
+		if i < 0 {
+			return "", fmt.Errorf("invalid item reference %q", value)
+		}
+		name, attr := rest[:i], rest[i+1:]
+		for _, it := range target.Items {
+			if strings.EqualFold(it.Type, itemType) && strings.EqualFold(it.DisplayName, name) {
+				switch attr {
+				case "id":
+					return it.Id, nil
+				case "name":
+					return it.DisplayName, nil
+				}
+				return "", fmt.Errorf("unknown item attribute in %q", value)
+			}
+		}
+		return "", fmt.Errorf("%s %q not found in workspace %s", itemType, name, target.Workspace.DisplayName)
+	}
+	// Not a variable, e.g. a regex replacement using $1.
+	return value, nil
+}
+
+// Applies reports whether any rule may change an item of this type and name.
+func (r *Replacer) Applies(item fabric.Item) bool {
+	for _, ru := range r.rules {
+		if ru.matchesItem(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replace applies the matching rules to the content of one definition file of item.
+func (r *Replacer) Replace(item fabric.Item, filePath, content string) string {
+	for _, ru := range r.rules {
+		if !ru.matchesItem(item) || !ru.matchesFile(filePath) {
+			continue
+		}
+		if ru.re != nil {
+			content = ru.re.ReplaceAllString(content, ru.replace)
+		} else {
+			content = strings.ReplaceAll(content, ru.FindValue, ru.replace)
+		}
+	}
+	return content
+}
+
+func (ru rule) matchesItem(item fabric.Item) bool {
+	types := ru.ItemType
+	if len(types) == 0 {
+		types = DefaultItemTypes
+	}
+	return containsFold(types, item.Type) && (len(ru.ItemName) == 0 || containsFold(ru.ItemName, item.DisplayName))
+}
+
+func (ru rule) matchesFile(filePath string) bool {
+	if len(ru.FilePath) == 0 {
+		return true
+	}
+	for _, p := range ru.FilePath {
+		// Patterns without a directory match the file name anywhere in the definition.
+		name := filePath
+		if !strings.Contains(p, "/") {
+			name = path.Base(filePath)
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,328 @@
+package parameters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+)
+
+var (
+	notebook = fabric.Item{Id: "nb-1", DisplayName: "Load orders", Type: fabric.ItemTypeNotebook}
+	pipeline = fabric.Item{Id: "dp-1", DisplayName: "Nightly", Type: fabric.ItemTypeDataPipeline}
+	target   = Target{
+		Environment: "feature",
+		Workspace:   fabric.Workspace{Id: "ws-feature", DisplayName: "Feature - orders"},
+		Items: []fabric.Item{
+			{Id: "lh-feature", DisplayName: "Bronze", Type: fabric.ItemTypeLakehouse},
+			{Id: "lh-dotted", DisplayName: "sales.v2", Type: fabric.ItemTypeLakehouse},
+		},
+	}
+)
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		item     fabric.Item
+		filePath string
+		content  string
+		want     string
+	}{
+		{
+			name: "literal",
+			yaml: `
+find_replace:
+  - find_value: "dev-sql.database.windows.net"
+    replace_value:
+      feature: "feature-sql.database.windows.net"`,
+			item:     notebook,
+			filePath: "notebook-content.py",
+			content:  `server = "dev-sql.database.windows.net"`,
+			want:     `server = "feature-sql.database.windows.net"`,
+		},
+		{
+			name: "literal is not a pattern",
+			yaml: `
+find_replace:
+  - find_value: "a.c"
+    replace_value:
+      feature: "x"`,
+			item:     notebook,
+			filePath: "notebook-content.py",
+			content:  "abc a.c",
+			want:     "abc x",
+		},
+		{
+			name: "regex with capture group",
+			yaml: `
+find_replace:
+  - find_value: 'Initial Catalog=(\w+)_dev'
+    is_regex: true
+    replace_value:
+      feature: "Initial Catalog=${1}_feature"`,
+			item:     notebook,
+			filePath: "notebook-content.py",
+			content:  "Initial Catalog=sales_dev;",
+			want:     "Initial Catalog=sales_feature;",
+		},
+		{
+			name: "workspace id",
+			yaml: `
+find_replace:
+  - find_value: "ws-dev"
+    replace_value:
+      feature: "$workspace.id"`,
+			item:     notebook,
+			filePath: "notebook-content.py",
+			content:  `"workspace": "ws-dev"`,
+			want:     `"workspace": "ws-feature"`,
+		},
+		{
+			name: "item id",
+			yaml: `
+find_replace:
+  - find_value: "lh-dev"
+    replace_value:
+      feature: "$items.Lakehouse.Bronze.id"`,
+			item:     notebook,
+			filePath: "notebook-content.py",
+			content:  `"lakehouse": "lh-dev"`,
+			want:     `"lakehouse": "lh-feature"`,
+		},
+		{
+			name: "item name with dots",
+			yaml: `
+find_replace:
+  - find_value: "lh-dev"
+    replace_value:
+      feature: "$items.lakehouse.sales.v2.id"`,
+			item:     notebook,
+			filePath: "notebook-content.py",
+			content:  "lh-dev",
+			want:     "lh-dotted",
+		},
+		{
+			name: "other environment only",
+			yaml: `
+find_replace:
+  - find_value: "dev"
+    replace_value:
+      prod: "prod"`,
+			item:     notebook,
+			filePath: "notebook-content.py",
+			content:  "dev",
+			want:     "dev",
+		},
+		{
+			name: "item type outside the defaults",
+			yaml: `
+find_replace:
+  - find_value: "dev"
+    replace_value:
+      feature: "feature"`,
+			item:     fabric.Item{DisplayName: "Bronze", Type: fabric.ItemTypeLakehouse},
+			filePath: "lakehouse.metadata.json",
+			content:  "dev",
+			want:     "dev",
+		},
+		{
+			name: "item type filter",
+			yaml: `
+find_replace:
+  - find_value: "dev"
+    replace_value:
+      feature: "feature"
+    item_type: DataPipeline`,
+			item:     notebook,
+			filePath: "notebook-content.py",
+			content:  "dev",
+			want:     "dev",
+		},
+		{
+			name: "item type list",
+			yaml: `
+find_replace:
+  - find_value: "dev"
+    replace_value:
+      feature: "feature"
+    item_type: [Notebook, DataPipeline]`,
+			item:     pipeline,
+			filePath: "pipeline-content.json",
+			content:  "dev",
+			want:     "feature",
+		},
+		{
+			name: "item name filter",
+			yaml: `
+find_replace:
+  - find_value: "dev"
+    replace_value:
+      feature: "feature"
+    item_name: "Load customers"`,
+			item:     notebook,
+			filePath: "notebook-content.py",
+			content:  "dev",
+			want:     "dev",
+		},
+		{
+			name: "file name pattern",
+			yaml: `
+find_replace:
+  - find_value: "dev"
+    replace_value:
+      feature: "feature"
+    file_path: "*.json"`,
+			item:     pipeline,
+			filePath: "definition/pipeline-content.json",
+			content:  "dev",
+			want:     "feature",
+		},
+		{
+			name: "file name pattern mismatch",
+			yaml: `
+find_replace:
+  - find_value: "dev"
+    replace_value:
+      feature: "feature"
+    file_path: "*.json"`,
+			item:     notebook,
+			filePath: "notebook-content.py",
+			content:  "dev",
+			want:     "dev",
+		},
+		{
+			name: "file path pattern with directory",
+			yaml: `
+find_replace:
+  - find_value: "dev"
+    replace_value:
+      feature: "feature"
+    file_path: "definition/*.json"`,
+			item:     pipeline,
+			filePath: "pipeline-content.json",
+			content:  "dev",
+			want:     "dev",
+		},
+		{
+			name: "rules apply in order",
+			yaml: `
+find_replace:
+  - find_value: "dev"
+    replace_value:
+      feature: "test"
+  - find_value: "test"
+    replace_value:
+      feature: "feature"`,
+			item:     notebook,
+			filePath: "notebook-content.py",
+			content:  "dev",
+			want:     "feature",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse([]byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			r, err := f.ForTarget(target)
+			if err != nil {
+				t.Fatalf("ForTarget: %v", err)
+			}
+			if got := r.Replace(tt.item, tt.filePath, tt.content); got != tt.want {
+				t.Errorf("Replace() = %q, want %q", got, tt.want)
+			}
+			if changed := tt.want != tt.content; changed && !r.Applies(tt.item) {
+				t.Errorf("Applies(%s) = false, want true", tt.item.DisplayName)
+			}
+		})
+	}
+}
+
+func TestForTargetErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace string
+		want    string
+	}{
+		{"unknown item", "$items.Lakehouse.Silver.id", `Lakehouse "Silver" not found`},
+		{"wrong item type", "$items.Warehouse.Bronze.id", `Warehouse "Bronze" not found`},
+		{"unknown attribute", "$items.Lakehouse.Bronze.path", "unknown item attribute"},
+		{"missing attribute", "$items.Lakehouse", "invalid item reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &File{FindReplace: []FindReplace{{
+				FindValue:    "x",
+				ReplaceValue: map[string]string{"feature": tt.replace},
+			}}}
+			_, err := f.ForTarget(target)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ForTarget() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+
+	// References are only resolved for the selected environment.
+	f := &File{FindReplace: []FindReplace{{
+		FindValue:    "x",
+		ReplaceValue: map[string]string{"prod": "$items.Lakehouse.Silver.id"},
+	}}}
+	if _, err := f.ForTarget(target); err != nil {
+		t.Errorf("ForTarget() with a reference for another environment: %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{
+			name: "invalid yaml",
+			yaml: "find_replace: [",
+			want: "parsing parameter file",
+		},
+		{
+			name: "missing find_value",
+			yaml: `
+find_replace:
+  - replace_value:
+      feature: "x"`,
+			want: "find_replace[0]: find_value is required",
+		},
+		{
+			name: "invalid regex",
+			yaml: `
+find_replace:
+  - find_value: "a"
+    replace_value:
+      feature: "b"
+  - find_value: "("
+    is_regex: true
+    replace_value:
+      feature: "x"`,
+			want: "find_replace[1]: error parsing regexp",
+		},
+		{
+			name: "invalid file_path",
+			yaml: `
+find_replace:
+  - find_value: "a"
+    replace_value:
+      feature: "b"
+    file_path: "["`,
+			want: `find_replace[0]: invalid file_path "["`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
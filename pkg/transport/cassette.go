@@ -0,0 +1,218 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Interaction is a recorded request and its response, stored as one JSON file in a cassette
+// directory.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the recorded part of a request.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the recorded part of a response.
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// redacted replaces credentials in recorded interactions.
+const redacted = "REDACTED"
+
+// sensitiveHeaders are replaced with redacted when recording.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Ms-Authorization-Auxiliary"}
+
+// sensitiveFields matches JSON string fields that carry tokens, such as GitHub App installation
+// tokens and OAuth responses.
+var sensitiveFields = regexp.MustCompile(`("(?:token|access_token|refresh_token|id_token|client_secret|password)"\s*:\s*)"[^"]*"`)
+
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range sensitiveHeaders {
+		if h.Get(k) != "" {
+			h.Set(k, redacted)
+		}
+	}
+	return h
+}
+
+func redactBody(b []byte) string {
+	return sensitiveFields.ReplaceAllString(string(b), `$1"`+redacted+`"`)
+}
+
+// RecordTransport writes every request and response passing through it to Dir, with
+// credentials redacted, so a session can be replayed with ReplayTransport.
+type RecordTransport struct {
+	Base http.RoundTripper
+	Dir  string
+
+	mu sync.Mutex
+	n  int
+}
+
+// NewRecordTransport wraps base (http.DefaultTransport if nil) and records into dir, which is
+// created if needed.
+func NewRecordTransport(base http.RoundTripper, dir string) (*RecordTransport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating cassette directory: %w", err)
+	}
+	existing, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordTransport{Base: base, Dir: dir, n: len(existing)}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	in := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: redactHeader(req.Header),
+			Body:   redactBody(reqBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     redactHeader(resp.Header),
+			Body:       redactBody(respBody),
+		},
+	}
+	if err := t.save(in); err != nil {
+		return nil, fmt.Errorf("recording %s %s: %w", req.Method, req.URL.Redacted(), err)
+	}
+	return resp, nil
+}
+
+func (t *RecordTransport) save(in Interaction) error {
+	b, err := json.MarshalIndent(in, "", "  ")
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.n++
+	return os.WriteFile(filepath.Join(t.Dir, fmt.Sprintf("%04d.json", t.n)), b, 0o600)
+}
+
+// ErrNotRecorded is returned by ReplayTransport for requests the cassette has no response for.
+var ErrNotRecorded = errors.New("no recorded response")
+
+// ReplayTransport serves responses recorded by RecordTransport without touching the network.
+// Each request is answered by the first unused interaction with the same method and URL, so
+// requests to different endpoints may be replayed in a different order than recorded.
+type ReplayTransport struct {
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewReplayTransport loads the cassette in dir.
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	files, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recorded interactions in %s", dir)
+	}
+	t := &ReplayTransport{used: make([]bool, len(files))}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var in Interaction
+		if err := json.Unmarshal(b, &in); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f, err)
+		}
+		t.interactions = append(t.interactions, &in)
+	}
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	url := req.URL.String()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, in := range t.interactions {
+		if t.used[i] || in.Request.Method != req.Method || in.Request.URL != url {
+			continue
+		}
+		t.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("replaying %s %s: %w", req.Method, url, ErrNotRecorded)
+}
+
+// cassetteFiles returns the interaction files in dir in recording order.
+func cassetteFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "installation token",
+			body: `{"token":"ghs_secret","expires_at":"2026-01-01T00:00:00Z"}`,
+			want: `{"token":"REDACTED","expires_at":"2026-01-01T00:00:00Z"}`,
+		},
+		{
+			name: "oauth response",
+			body: `{"token_type":"Bearer","access_token": "eyJ0eXAi", "refresh_token":"r1","id_token":"i1"}`,
+			want: `{"token_type":"Bearer","access_token": "REDACTED", "refresh_token":"REDACTED","id_token":"REDACTED"}`,
+		},
+		{
+			name: "client secret and password",
+			body: `{"client_secret" : "s3cret","password":"hunter2"}`,
+			want: `{"client_secret" : "REDACTED","password":"REDACTED"}`,
+		},
+		{
+			name: "other fields",
+			body: `{"displayName":"token","description":"access_token"}`,
+			want: `{"displayName":"token","description":"access_token"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactBody([]byte(tt.body)); got != tt.want {
+				t.Errorf("redactBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		h := http.Header{}
+		h.Set("Set-Cookie", "session=abc")
+		h.Set("Content-Type", "application/json")
+		return &http.Response{StatusCode: http.StatusCreated, Header: h, Body: io.NopCloser(strings.NewReader(`{"token":"ghs_secret"}`))}, nil
+	})
+	rec, err := NewRecordTransport(base, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/app/installations/1/access_tokens", strings.NewReader(`{"password":"hunter2"}`))
+	req.Header.Set("Authorization", "Bearer eyJ0eXAi")
+	resp, err := rec.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The caller still gets the real response.
+	if b, _ := io.ReadAll(resp.Body); string(b) != `{"token":"ghs_secret"}` {
+		t.Errorf("recorded response body = %s", b)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "0001.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"ghs_secret", "hunter2", "eyJ0eXAi", "session=abc"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, b)
+		}
+	}
+
+	replay, err := NewReplayTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest(http.MethodPost, "https://api.github.com/app/installations/1/access_tokens", nil)
+	resp, err = replay.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("replayed %d %v, want 201 with the recorded headers", resp.StatusCode, resp.Header)
+	}
+	// Each interaction answers one request.
+	if _, err := replay.RoundTrip(req); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("second replay error = %v, want ErrNotRecorded", err)
+	}
+}
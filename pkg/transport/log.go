@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxLoggedBody caps the request and response bodies written to the log.
+const maxLoggedBody = 8 << 10
+
+// requestIdHeaders are the response headers services use to identify a request to support.
+var requestIdHeaders = []string{"requestid", "x-ms-request-id", "ActivityId", "x-ms-operation-id", "X-GitHub-Request-Id"}
+
+// LogTransport logs every request with its status, latency, request ids and redacted bodies.
+// Successful requests are logged at debug level, failures at warn level.
+type LogTransport struct {
+	Base   http.RoundTripper
+	Logger *slog.Logger
+}
+
+// NewLogTransport wraps base (http.DefaultTransport if nil) and logs to logger.
+func NewLogTransport(base http.RoundTripper, logger *slog.Logger) *LogTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &LogTransport{Base: base, Logger: logger}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *LogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.Redacted()),
+	}
+	if len(reqBody) > 0 {
+		attrs = append(attrs, slog.String("request_body", logBody(reqBody)))
+	}
+
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+	attrs = append(attrs, slog.Duration("latency", time.Since(start)))
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		t.Logger.LogAttrs(req.Context(), slog.LevelWarn, "http request failed", attrs...)
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	for _, h := range requestIdHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			attrs = append(attrs, slog.String(h, v))
+		}
+	}
+	if len(respBody) > 0 {
+		attrs = append(attrs, slog.String("response_body", logBody(respBody)))
+	}
+	level := slog.LevelDebug
+	if resp.StatusCode >= 400 {
+		level = slog.LevelWarn
+	}
+	t.Logger.LogAttrs(req.Context(), level, "http request", attrs...)
+	return resp, nil
+}
+
+// logBody redacts credentials and truncates b for logging.
+func logBody(b []byte) string {
+	s := redactBody(b)
+	if len(s) > maxLoggedBody {
+		s = s[:maxLoggedBody] + "...(truncated)"
+	}
+	return s
+}
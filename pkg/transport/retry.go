@@ -0,0 +1,146 @@
+// Package transport provides the http.RoundTrippers shared by the Fabric and Azure DevOps
+// clients: retries, and recording and replaying sessions.
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero disables retries.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles on every attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the computed backoff and any Retry-After the server asks for.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy retries up to five times, backing off from 1s to at most 60s.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	BaseDelay:  time.Second,
+	MaxDelay:   60 * time.Second,
+}
+
+type idempotentKey struct{}
+
+// WithIdempotent marks requests made with ctx as safe to retry even if their method is not
+// idempotent, e.g. a POST that only reads data.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// RetryTransport retries throttled and transiently failing requests with exponential backoff,
+// honoring Retry-After.
+//
+// Requests with idempotent methods (GET, HEAD, PUT, DELETE, OPTIONS) are retried on 429, 5xx
+// gateway/availability errors and network errors. Other requests, such as POST /workspaces, are
+// only retried on 429, where the service guarantees the request was not processed, unless
+// their context was marked with WithIdempotent.
+type RetryTransport struct {
+	Base   http.RoundTripper
+	Policy RetryPolicy
+}
+
+// NewRetryTransport wraps base (http.DefaultTransport if nil) with the given policy.
+func NewRetryTransport(base http.RoundTripper, policy RetryPolicy) *RetryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RetryTransport{Base: base, Policy: policy}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	idempotent := isIdempotent(req)
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	attemptReq := req
+	for attempt := 0; ; attempt++ {
+		resp, err := t.Base.RoundTrip(attemptReq)
+		if attempt >= t.Policy.MaxRetries || !replayable || !shouldRetry(resp, err, idempotent) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = min(d, t.Policy.MaxDelay)
+			}
+			// Drain so the connection can be reused.
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		// RoundTrippers must not modify the caller's request, so retry on a clone.
+		attemptReq = req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+	}
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	v, _ := req.Context().Value(idempotentKey{}).(bool)
+	return v
+}
+
+func shouldRetry(resp *http.Response, err error, idempotent bool) bool {
+	if err != nil {
+		// The request may have reached the server, so only retry what is safe to repeat.
+		return idempotent && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrNotRecorded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// backoff returns the exponential delay for attempt with up to 20% jitter.
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	d := t.Policy.BaseDelay << attempt
+	if d <= 0 || d > t.Policy.MaxDelay {
+		d = t.Policy.MaxDelay
+	}
+	return d - time.Duration(rand.Int64N(int64(d)/5+1))
+}
+
+// parseRetryAfter accepts both the delay-seconds and HTTP-date forms of Retry-After.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
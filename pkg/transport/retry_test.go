@@ -0,0 +1,171 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripFunc is an http.RoundTripper backed by a function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// scripted answers successive requests with the given statuses, or with a network error for
+// status 0, and records the request bodies it saw.
+type scripted struct {
+	statuses   []int
+	retryAfter string
+	bodies     []string
+}
+
+func (s *scripted) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		body = string(b)
+	}
+	s.bodies = append(s.bodies, body)
+	status := s.statuses[min(len(s.bodies), len(s.statuses))-1]
+	if status == 0 {
+		return nil, errors.New("connection reset")
+	}
+	resp := &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}
+	if s.retryAfter != "" {
+		resp.Header.Set("Retry-After", s.retryAfter)
+	}
+	return resp, nil
+}
+
+var testPolicy = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		idempotent bool
+		statuses   []int
+		wantStatus int
+		wantCalls  int
+	}{
+		{"get succeeds", http.MethodGet, false, []int{200}, 200, 1},
+		{"get retried on 503", http.MethodGet, false, []int{503, 502, 200}, 200, 3},
+		{"get retried on network error", http.MethodGet, false, []int{0, 200}, 200, 2},
+		{"get gives up", http.MethodGet, false, []int{503}, 503, 4},
+		{"get not retried on 400", http.MethodGet, false, []int{400, 200}, 400, 1},
+		{"post retried on 429", http.MethodPost, false, []int{429, 201}, 201, 2},
+		{"post not retried on 503", http.MethodPost, false, []int{503, 201}, 503, 1},
+		{"idempotent post retried on 503", http.MethodPost, true, []int{503, 200}, 200, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &scripted{statuses: tt.statuses}
+			ctx := context.Background()
+			if tt.idempotent {
+				ctx = WithIdempotent(ctx)
+			}
+			req, _ := http.NewRequestWithContext(ctx, tt.method, "https://example.com/v1/workspaces", strings.NewReader(`{"displayName":"ws"}`))
+			resp, err := NewRetryTransport(base, testPolicy).RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if len(base.bodies) != tt.wantCalls {
+				t.Errorf("got %d attempts, want %d", len(base.bodies), tt.wantCalls)
+			}
+			for i, b := range base.bodies {
+				if b != `{"displayName":"ws"}` {
+					t.Errorf("attempt %d sent body %q", i+1, b)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransportNetworkErrorOnPost(t *testing.T) {
+	base := &scripted{statuses: []int{0, 201}}
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/v1/workspaces", strings.NewReader("{}"))
+	if _, err := NewRetryTransport(base, testPolicy).RoundTrip(req); err == nil {
+		t.Fatal("RoundTrip succeeded, want the network error")
+	}
+	if len(base.bodies) != 1 {
+		t.Errorf("got %d attempts, want 1", len(base.bodies))
+	}
+}
+
+func TestRetryTransportCapsRetryAfter(t *testing.T) {
+	base := &scripted{statuses: []int{429, 200}, retryAfter: "120"}
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/v1/workspaces", nil)
+	start := time.Now()
+	resp, err := NewRetryTransport(base, testPolicy).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %s, want at most the %s max delay", elapsed, testPolicy.MaxDelay)
+	}
+}
+
+func TestRetryTransportStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	base := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		cancel()
+		return &http.Response{StatusCode: 503, Header: http.Header{}, Body: http.NoBody}, nil
+	})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com/v1/workspaces", nil)
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Minute, MaxDelay: time.Minute}
+	if _, err := NewRetryTransport(base, policy).RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("RoundTrip error = %v, want context.Canceled", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	rt := NewRetryTransport(nil, RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second})
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		for range 20 {
+			// Jitter takes off up to 20%.
+			if d := rt.backoff(attempt); d > want || d < want-want/5 {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, d, want-want/5, want)
+			}
+		}
+	}
+	// Shifting far enough overflows; that must still land on the cap.
+	if d := rt.backoff(80); d > 10*time.Second || d < 8*time.Second {
+		t.Errorf("backoff(80) = %s, want the capped delay", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"30", 30 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("parseRetryAfter(%q) = %s, %v; want %s, %v", tt.value, got, ok, tt.want, tt.wantOk)
+		}
+	}
+
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got, ok := parseRetryAfter(future); !ok || got <= 58*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %s, %v; want about a minute", future, got, ok)
+	}
+}
@@ -0,0 +1,20 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/amaliebjorgen/fabricant/pkg/gitprovider"
+)
+
+// selectGitProvider sets Git from the parent's git connection unless it was provided.
+func (o *Options) selectGitProvider() error {
+	if o.Git != nil {
+		return nil
+	}
+	p, err := gitprovider.ForRepository(o.Parent.GitProviderDetails, gitprovider.Clients{DevOps: o.DevOps, GitHub: o.GitHub})
+	if err != nil {
+		return fmt.Errorf("workflow: %w", err)
+	}
+	o.Git = p
+	return nil
+}
@@ -0,0 +1,59 @@
+package workflow
+
+import "time"
+
+// Step identifies a stage of the feature environment workflow.
+type Step string
+
+const (
+	StepResolveBaseCommit Step = "ResolveBaseCommit"
+	StepCreateBranch      Step = "CreateBranch"
+	StepCreateWorkspace   Step = "CreateWorkspace"
+	StepConnectGit        Step = "ConnectGit"
+	StepInitializeGit     Step = "InitializeGit"
+	StepUpdateFromGit     Step = "UpdateFromGit"
+	StepRebindItems       Step = "RebindItems"
+	StepApplyParameters   Step = "ApplyParameters"
+
+	// Compensation steps run by Rollback.
+	StepDeleteWorkspace Step = "DeleteWorkspace"
+	StepDeleteBranch    Step = "DeleteBranch"
+)
+
+// EventKind describes what happened to a step.
+type EventKind int
+
+const (
+	EventStepStarted EventKind = iota
+	EventStepProgress
+	EventStepCompleted
+	EventStepFailed
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventStepStarted:
+		return "Started"
+	case EventStepProgress:
+		return "Progress"
+	case EventStepCompleted:
+		return "Completed"
+	case EventStepFailed:
+		return "Failed"
+	}
+	return "Unknown"
+}
+
+// Event is emitted to Options.OnEvent as the workflow advances.
+type Event struct {
+	Step    Step
+	Kind    EventKind
+	Time    time.Time
+	Message string
+	// PercentComplete is set on progress events of long-running steps, -1 if unknown.
+	PercentComplete int
+	// Err is set on EventStepFailed.
+	Err error
+	// Skipped is set when the step already completed in an earlier attempt of a resumed run.
+	Skipped bool
+}
@@ -0,0 +1,222 @@
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+)
+
+// RunStatus is the lifecycle state of a persisted run.
+type RunStatus string
+
+const (
+	RunRunning    RunStatus = "Running"
+	RunSucceeded  RunStatus = "Succeeded"
+	RunFailed     RunStatus = "Failed"
+	RunRolledBack RunStatus = "RolledBack"
+)
+
+// Run is the persisted journal of a CreateFeatureEnvironment run. It holds the inputs and the
+// progress reached so far, which is everything ResumeFeatureEnvironment needs to continue.
+type Run struct {
+	Id        string    `json:"id"`
+	Status    RunStatus `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	Parent        fabric.Workspace `json:"parent"`
+	BranchName    string           `json:"branchName"`
+	WorkspaceName string           `json:"workspaceName"`
+	CapacityId    string           `json:"capacityId,omitempty"`
+	// ParametersFile and Environment are the parameter rules the run applies, so a resumed
+	// run applies the same ones.
+	ParametersFile string `json:"parametersFile,omitempty"`
+	Environment    string `json:"environment,omitempty"`
+
+	Result Result `json:"result"`
+}
+
+// Unfinished reports whether the run stopped before completing and can be resumed. Runs whose
+// rollback started are not resumable, as the resources they created may be gone.
+func (r *Run) Unfinished() bool {
+	return (r.Status == RunRunning || r.Status == RunFailed) && !r.Result.RollbackStarted()
+}
+
+// PartiallyRolledBack reports whether the run's rollback started but left something behind.
+// Such a run can only be rolled back, with RollbackRun.
+func (r *Run) PartiallyRolledBack() bool {
+	return r.Status == RunFailed && r.Result.RollbackStarted() && r.Result.NeedsRollback()
+}
+
+// LastStep returns the last step that completed, or "" if none did.
+func (r *Run) LastStep() Step {
+	if len(r.Result.Completed) == 0 {
+		return ""
+	}
+	return r.Result.Completed[len(r.Result.Completed)-1]
+}
+
+// RunStore persists runs as JSON files in a directory.
+type RunStore struct {
+	dir string
+}
+
+// NewRunStore returns a store that keeps run files in dir.
+func NewRunStore(dir string) *RunStore {
+	return &RunStore{dir: dir}
+}
+
+// DefaultRunStore returns the store in the user's config directory, e.g. ~/.config/fabricant/runs.
+func DefaultRunStore() (*RunStore, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("locating config directory: %w", err)
+	}
+	return NewRunStore(filepath.Join(dir, "fabricant", "runs")), nil
+}
+
+func (s *RunStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Save writes the run, replacing any previous version atomically.
+func (s *RunStore) Save(run *Run) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path(run.Id) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(run.Id))
+}
+
+// Load reads the run with the given id.
+func (s *RunStore) Load(id string) (*Run, error) {
+	b, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("run %s not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(b, &run); err != nil {
+		return nil, fmt.Errorf("reading run %s: %w", id, err)
+	}
+	return &run, nil
+}
+
+// Delete removes the run file.
+func (s *RunStore) Delete(id string) error {
+	return os.Remove(s.path(id))
+}
+
+// List returns all stored runs, most recent first.
+func (s *RunStore) List() ([]*Run, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*Run
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		run, err := s.Load(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return runs, nil
+}
+
+// Unfinished returns the runs that can be resumed, most recent first.
+func (s *RunStore) Unfinished() ([]*Run, error) {
+	return s.filter((*Run).Unfinished)
+}
+
+// PartiallyRolledBack returns the runs whose rollback is incomplete, most recent first.
+func (s *RunStore) PartiallyRolledBack() ([]*Run, error) {
+	return s.filter((*Run).PartiallyRolledBack)
+}
+
+func (s *RunStore) filter(keep func(*Run) bool) ([]*Run, error) {
+	runs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var out []*Run
+	for _, r := range runs {
+		if keep(r) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func newRunId() string {
+	return fmt.Sprintf("%s-%04x", time.Now().Format("20060102-150405"), rand.IntN(0x10000))
+}
+
+// journal checkpoints a run to its store. A nil journal is a no-op.
+type journal struct {
+	store *RunStore
+	run   *Run
+}
+
+func (j *journal) checkpoint(res Result) error {
+	if j == nil {
+		return nil
+	}
+	j.run.Result = res
+	j.run.UpdatedAt = time.Now()
+	if err := j.store.Save(j.run); err != nil {
+		return fmt.Errorf("saving run state: %w", err)
+	}
+	return nil
+}
+
+func (j *journal) finish(res Result, runErr error) error {
+	if j == nil {
+		return nil
+	}
+	if runErr == nil {
+		j.run.Status = RunSucceeded
+		j.run.Error = ""
+		return j.checkpoint(res)
+	}
+	j.run.Status = RunFailed
+	j.run.Error = runErr.Error()
+	return j.rolledBack(res)
+}
+
+// rolledBack marks the run as rolled back once nothing it created is left behind.
+func (j *journal) rolledBack(res Result) error {
+	if j == nil {
+		return nil
+	}
+	if res.RollbackStarted() && !res.NeedsRollback() {
+		j.run.Status = RunRolledBack
+	}
+	return j.checkpoint(res)
+}
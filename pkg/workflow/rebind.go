@@ -0,0 +1,140 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/amaliebjorgen/fabricant/pkg/parameters"
+)
+
+// rebindItemTypes are the item types whose definitions reference Lakehouses and other items by
+// id, e.g. a notebook's default and attached lakehouses.
+var rebindItemTypes = map[string]bool{
+	fabric.ItemTypeNotebook:           true,
+	fabric.ItemTypeDataPipeline:       true,
+	fabric.ItemTypeSparkJobDefinition: true,
+}
+
+// mapItems maps the ids of the parent workspace and its items to their counterparts in the
+// feature workspace, matching items by type and display name. Lakehouses that git did not
+// create in the feature workspace are created empty so nothing is left pointing at the
+// parent's data.
+func mapItems(ctx context.Context, api fabric.API, parentId, wsId string) (map[string]string, error) {
+	parentItems, err := api.ListItems(ctx, parentId, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing parent items: %w", err)
+	}
+	items, err := api.ListItems(ctx, wsId, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing feature items: %w", err)
+	}
+	byName := map[string]fabric.Item{}
+	for _, it := range items {
+		byName[itemKey(it)] = it
+	}
+
+	mapping := map[string]string{strings.ToLower(parentId): wsId}
+	for _, p := range parentItems {
+		if it, ok := byName[itemKey(p)]; ok {
+			mapping[strings.ToLower(p.Id)] = it.Id
+			continue
+		}
+		if p.Type != fabric.ItemTypeLakehouse {
+			continue
+		}
+		lh, err := api.CreateItem(ctx, wsId, fabric.CreateItemRequest{DisplayName: p.DisplayName, Type: p.Type, Description: p.Description})
+		if err != nil {
+			return nil, fmt.Errorf("creating lakehouse %s: %w", p.DisplayName, err)
+		}
+		mapping[strings.ToLower(p.Id)] = lh.Id
+	}
+	return mapping, nil
+}
+
+func itemKey(it fabric.Item) string {
+	return it.Type + "/" + strings.ToLower(it.DisplayName)
+}
+
+// rebindItems rewrites every reference to a key of mapping in the definitions of the feature
+// workspace's notebooks, pipelines and Spark job definitions. References to other workspaces
+// are left alone. onItem is called before each item is processed.
+func rebindItems(ctx context.Context, api fabric.API, wsId string, mapping map[string]string, onItem func(item fabric.Item, i, n int)) error {
+	ids := make([]string, 0, len(mapping))
+	for id := range mapping {
+		ids = append(ids, regexp.QuoteMeta(id))
+	}
+	re := regexp.MustCompile("(?i)" + strings.Join(ids, "|"))
+	replace := func(id string) string {
+		return mapping[strings.ToLower(id)]
+	}
+
+	items, err := api.ListItems(ctx, wsId, "")
+	if err != nil {
+		return fmt.Errorf("listing feature items: %w", err)
+	}
+	var targets []fabric.Item
+	for _, it := range items {
+		if rebindItemTypes[it.Type] {
+			targets = append(targets, it)
+		}
+	}
+	return rewriteDefinitions(ctx, api, wsId, targets, func(_ fabric.Item, _ string, content string) string {
+		return re.ReplaceAllStringFunc(content, replace)
+	}, onItem)
+}
+
+// rewriteDefinitions passes every file of the items' definitions through rewrite and updates
+// the items whose content changed.
+func rewriteDefinitions(ctx context.Context, api fabric.API, wsId string, items []fabric.Item, rewrite func(item fabric.Item, path, content string) string, onItem func(item fabric.Item, i, n int)) error {
+	for i, it := range items {
+		if onItem != nil {
+			onItem(it, i, len(items))
+		}
+		def, err := api.GetItemDefinition(ctx, wsId, it.Id, "")
+		if err != nil {
+			return fmt.Errorf("getting definition of %s %s: %w", it.Type, it.DisplayName, err)
+		}
+		changed := false
+		for j := range def.Parts {
+			part := &def.Parts[j]
+			content, err := part.Decode()
+			if err != nil {
+				return fmt.Errorf("decoding %s of %s: %w", part.Path, it.DisplayName, err)
+			}
+			if rewritten := rewrite(it, part.Path, string(content)); rewritten != string(content) {
+				part.SetContent([]byte(rewritten))
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := api.UpdateItemDefinition(ctx, wsId, it.Id, *def); err != nil {
+			return fmt.Errorf("updating definition of %s %s: %w", it.Type, it.DisplayName, err)
+		}
+	}
+	return nil
+}
+
+// applyParameters applies the parameter file rules for the target environment to the feature
+// workspace's item definitions.
+func applyParameters(ctx context.Context, api fabric.API, ws fabric.Workspace, file *parameters.File, env string, onItem func(item fabric.Item, i, n int)) error {
+	items, err := api.ListItems(ctx, ws.Id, "")
+	if err != nil {
+		return fmt.Errorf("listing feature items: %w", err)
+	}
+	rep, err := file.ForTarget(parameters.Target{Environment: env, Workspace: ws, Items: items})
+	if err != nil {
+		return fmt.Errorf("resolving parameters: %w", err)
+	}
+	var targets []fabric.Item
+	for _, it := range items {
+		if rep.Applies(it) {
+			targets = append(targets, it)
+		}
+	}
+	return rewriteDefinitions(ctx, api, ws.Id, targets, rep.Replace, onItem)
+}
@@ -0,0 +1,101 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/amaliebjorgen/fabricant/pkg/gitprovider"
+)
+
+// Rollback removes the resources created by a failed CreateFeatureEnvironment run: the feature
+// workspace first, then the feature branch. Both are attempted even if one fails, and the
+// compensating steps are recorded in res so a retried Rollback only redoes what is left.
+// If opts.Store is set, the run's journal is updated as well.
+func Rollback(ctx context.Context, opts Options, res *Result) error {
+	var j *journal
+	if opts.Store != nil && res.RunId != "" {
+		run, err := opts.Store.Load(res.RunId)
+		if err != nil {
+			return err
+		}
+		j = &journal{store: opts.Store, run: run}
+	}
+	err := rollback(ctx, opts, j, res)
+	if jErr := j.rolledBack(*res); jErr != nil {
+		err = errors.Join(err, jErr)
+	}
+	return err
+}
+
+// RollbackRun removes what a journaled run left behind, e.g. after its rollback was
+// interrupted. The inputs are taken from run; only the clients, Store and OnEvent are used
+// from opts.
+func RollbackRun(ctx context.Context, opts Options, run *Run) (Result, error) {
+	if !run.Result.NeedsRollback() {
+		return run.Result, fmt.Errorf("run %s left nothing to roll back", run.Id)
+	}
+	parent := run.Parent
+	opts.Parent = &parent
+	res := run.Result
+	var j *journal
+	if opts.Store != nil {
+		j = &journal{store: opts.Store, run: run}
+	}
+	err := rollback(ctx, opts, j, &res)
+	if jErr := j.rolledBack(res); jErr != nil {
+		err = errors.Join(err, jErr)
+	}
+	return res, err
+}
+
+func rollback(ctx context.Context, opts Options, j *journal, res *Result) error {
+	if !res.NeedsRollback() {
+		return nil
+	}
+	if res.branchLeft() {
+		if err := opts.selectGitProvider(); err != nil {
+			return err
+		}
+	}
+	r := runner{onEvent: opts.OnEvent, res: res, journal: j}
+	var errs []error
+
+	if res.workspaceLeft() && res.Workspace != nil {
+		ws := res.Workspace
+		err := r.run(StepDeleteWorkspace, fmt.Sprintf("Deleting workspace %s", ws.DisplayName), func() error {
+			err := opts.Fabric.DeleteWorkspace(ctx, ws.Id)
+			if fabric.IsErrorCode(err, fabric.ErrorCodeWorkspaceNotFound) {
+				// Deleted by hand, or by an earlier attempt whose response was lost.
+				r.emit(StepDeleteWorkspace, EventStepProgress, "Workspace already deleted", 100, nil)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("deleting workspace %s: %w", ws.Id, err)
+			}
+			return nil
+		})
+		errs = append(errs, err)
+	}
+
+	if res.branchLeft() {
+		err := r.run(StepDeleteBranch, fmt.Sprintf("Deleting branch %s", res.BranchName), func() error {
+			err := opts.Git.DeleteBranch(ctx, res.BranchName)
+			if gitprovider.IsBranchNotFound(err) {
+				r.emit(StepDeleteBranch, EventStepProgress, "Branch already deleted", 100, nil)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("deleting branch %s: %w", res.BranchName, err)
+			}
+			return nil
+		})
+		errs = append(errs, err)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("rollback incomplete: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,455 @@
+// Package workflow orchestrates multi-step operations across the Fabric and git provider
+// clients, such as creating a feature environment from a parent dev workspace.
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amaliebjorgen/fabricant/pkg/devops"
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/amaliebjorgen/fabricant/pkg/github"
+	"github.com/amaliebjorgen/fabricant/pkg/gitprovider"
+	"github.com/amaliebjorgen/fabricant/pkg/parameters"
+)
+
+// Options configures CreateFeatureEnvironment.
+type Options struct {
+	Fabric fabric.API
+	// Git performs the branch operations. If nil, it is selected from the parent's git
+	// provider type, using DevOps for Azure DevOps repositories and GitHub for GitHub ones.
+	Git    gitprovider.Provider
+	DevOps devops.API
+	GitHub github.API
+
+	// Parent is the dev workspace to branch from. Its GitProviderDetails must be populated.
+	Parent        *fabric.Workspace
+	BranchName    string
+	WorkspaceName string
+	// CapacityId defaults to the parent workspace's capacity.
+	CapacityId string
+
+	// Parameters, if set, are applied to the new workspace's item definitions for Environment
+	// after it has been synced from git.
+	Parameters  *parameters.File
+	Environment string
+	// ParametersFile is the path Parameters was loaded from. It is journaled so that a
+	// resumed run loads the same rules.
+	ParametersFile string
+
+	// RollbackOnFailure deletes the created workspace and branch if a later step fails.
+	RollbackOnFailure bool
+
+	// Store, if set, journals the run after every step so it can be resumed.
+	Store *RunStore
+
+	// OnEvent, if set, is called synchronously for every step event.
+	OnEvent func(Event)
+}
+
+// Result describes the feature environment that was created.
+type Result struct {
+	// RunId identifies the journaled run, empty if Options.Store was not set.
+	RunId        string            `json:"runId,omitempty"`
+	BranchName   string            `json:"branchName"`
+	BaseCommitId string            `json:"baseCommitId,omitempty"`
+	Workspace    *fabric.Workspace `json:"workspace,omitempty"`
+	// GitInit is the outcome of initializing the git connection, which drives the update step.
+	GitInit *fabric.InitializeGitConnectionResponse `json:"gitInit,omitempty"`
+	// OperationId is the id of the update-from-git operation, empty if it completed synchronously.
+	OperationId string `json:"operationId,omitempty"`
+	// ItemMapping maps the ids of the parent workspace and its items to the feature
+	// workspace's, as used to rebind Lakehouse references.
+	ItemMapping map[string]string `json:"itemMapping,omitempty"`
+	// Completed lists the steps that finished successfully, in order.
+	Completed []Step `json:"completed,omitempty"`
+}
+
+// HasCompleted reports whether step finished successfully.
+func (r Result) HasCompleted(step Step) bool {
+	for _, s := range r.Completed {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsRollback reports whether the run left behind a branch or workspace that Rollback would remove.
+func (r Result) NeedsRollback() bool {
+	return r.workspaceLeft() || r.branchLeft()
+}
+
+// RollbackStarted reports whether any rollback step completed. Such a run cannot be resumed.
+func (r Result) RollbackStarted() bool {
+	return r.HasCompleted(StepDeleteWorkspace) || r.HasCompleted(StepDeleteBranch)
+}
+
+func (r Result) workspaceLeft() bool {
+	return r.HasCompleted(StepCreateWorkspace) && !r.HasCompleted(StepDeleteWorkspace)
+}
+
+func (r Result) branchLeft() bool {
+	return r.HasCompleted(StepCreateBranch) && !r.HasCompleted(StepDeleteBranch)
+}
+
+// validate checks the required options and selects the git provider.
+func (o *Options) validate() error {
+	switch {
+	case o.Fabric == nil:
+		return errors.New("workflow: Fabric client is required")
+	case o.Parent == nil:
+		return errors.New("workflow: parent workspace is required")
+	case o.Parent.GitProviderDetails == nil || o.Parent.GitProviderDetails.GitProviderType == "":
+		return fmt.Errorf("workflow: workspace %q does not have git integration", o.Parent.DisplayName)
+	case o.BranchName == "":
+		return errors.New("workflow: branch name is required")
+	case o.WorkspaceName == "":
+		return errors.New("workflow: workspace name is required")
+	case o.Parameters != nil && o.Environment == "":
+		return errors.New("workflow: environment is required with parameters")
+	}
+	return o.selectGitProvider()
+}
+
+// runner emits step events to the configured callback, records completed steps and
+// checkpoints them to the journal.
+type runner struct {
+	onEvent func(Event)
+	res     *Result
+	journal *journal
+}
+
+func (r runner) emit(step Step, kind EventKind, msg string, pct int, err error) {
+	if r.onEvent == nil {
+		return
+	}
+	r.onEvent(Event{Step: step, Kind: kind, Time: time.Now(), Message: msg, PercentComplete: pct, Err: err})
+}
+
+// run wraps a single step, emitting started/completed/failed events around fn. Steps that
+// already completed in a resumed run are reported as completed without running fn again.
+func (r runner) run(step Step, msg string, fn func() error) error {
+	if r.res.HasCompleted(step) {
+		if r.onEvent != nil {
+			now := time.Now()
+			r.onEvent(Event{Step: step, Kind: EventStepStarted, Time: now, Message: msg, PercentComplete: -1, Skipped: true})
+			r.onEvent(Event{Step: step, Kind: EventStepCompleted, Time: now, Message: msg, PercentComplete: 100, Skipped: true})
+		}
+		return nil
+	}
+
+	r.emit(step, EventStepStarted, msg, -1, nil)
+	if err := fn(); err != nil {
+		r.emit(step, EventStepFailed, msg, -1, err)
+		return err
+	}
+	r.res.Completed = append(r.res.Completed, step)
+	if err := r.checkpoint(); err != nil {
+		r.emit(step, EventStepFailed, msg, -1, err)
+		return err
+	}
+	r.emit(step, EventStepCompleted, msg, 100, nil)
+	return nil
+}
+
+// itemProgress returns a callback reporting per-item progress of step.
+func (r runner) itemProgress(step Step) func(item fabric.Item, i, n int) {
+	return func(item fabric.Item, i, n int) {
+		r.emit(step, EventStepProgress, item.DisplayName, i*100/n, nil)
+	}
+}
+
+// checkpoint saves the progress made so far, e.g. an operation id before polling it.
+func (r runner) checkpoint() error {
+	return r.journal.checkpoint(*r.res)
+}
+
+// CreateFeatureEnvironment creates a feature branch from the parent workspace's branch, creates
+// a new workspace, connects it to the branch, syncs its content from git and rebinds its
+// notebooks and pipelines from the parent's Lakehouses to its own.
+//
+// On failure the returned Result records the steps that did complete so the caller can pass it
+// to Rollback, unless opts.RollbackOnFailure already did so.
+func CreateFeatureEnvironment(ctx context.Context, opts Options) (Result, error) {
+	if err := opts.validate(); err != nil {
+		return Result{}, err
+	}
+	if opts.CapacityId == "" {
+		opts.CapacityId = opts.Parent.CapacityId
+	}
+
+	res := Result{BranchName: opts.BranchName}
+	var j *journal
+	if opts.Store != nil {
+		now := time.Now()
+		res.RunId = newRunId()
+		j = &journal{store: opts.Store, run: &Run{
+			Id:            res.RunId,
+			Status:        RunRunning,
+			StartedAt:     now,
+			Parent:        *opts.Parent,
+			BranchName:    opts.BranchName,
+			WorkspaceName: opts.WorkspaceName,
+			CapacityId:    opts.CapacityId,
+
+			ParametersFile: opts.ParametersFile,
+			Environment:    opts.Environment,
+		}}
+		if err := j.checkpoint(res); err != nil {
+			return res, err
+		}
+	}
+	return execute(ctx, opts, j, res, false)
+}
+
+// ResumeFeatureEnvironment continues a journaled run from the last step that completed. The
+// inputs, including the parameter file and environment, are taken from run; only the clients,
+// Store, RollbackOnFailure and OnEvent are used from opts.
+func ResumeFeatureEnvironment(ctx context.Context, opts Options, run *Run) (Result, error) {
+	if run.PartiallyRolledBack() {
+		return run.Result, fmt.Errorf("run %s was partially rolled back and can only be rolled back", run.Id)
+	}
+	if !run.Unfinished() {
+		return run.Result, fmt.Errorf("run %s is %s and cannot be resumed", run.Id, run.Status)
+	}
+	if err := opts.fromRun(run); err != nil {
+		return run.Result, err
+	}
+	if err := opts.validate(); err != nil {
+		return run.Result, err
+	}
+
+	var j *journal
+	if opts.Store != nil {
+		j = &journal{store: opts.Store, run: run}
+		run.Status = RunRunning
+		if err := j.checkpoint(run.Result); err != nil {
+			return run.Result, err
+		}
+	}
+	return execute(ctx, opts, j, run.Result, true)
+}
+
+// fromRun replaces the inputs in opts with those journaled in run.
+func (o *Options) fromRun(run *Run) error {
+	parent := run.Parent
+	o.Parent = &parent
+	o.BranchName = run.BranchName
+	o.WorkspaceName = run.WorkspaceName
+	o.CapacityId = run.CapacityId
+	o.ParametersFile = run.ParametersFile
+	o.Environment = run.Environment
+	o.Parameters = nil
+	if run.ParametersFile != "" && !run.Result.HasCompleted(StepApplyParameters) {
+		f, err := parameters.Load(run.ParametersFile)
+		if err != nil {
+			return err
+		}
+		o.Parameters = f
+	}
+	return nil
+}
+
+// execute runs the workflow from res. A resumed run adopts a branch or workspace that an
+// interrupted step created before its progress was saved.
+func execute(ctx context.Context, opts Options, j *journal, res Result, resumed bool) (Result, error) {
+	err := createFeatureEnvironment(ctx, opts, j, &res, resumed)
+	if err != nil && opts.RollbackOnFailure && res.NeedsRollback() {
+		// The original context may be the reason we failed, so clean up on a fresh one.
+		if rbErr := rollback(context.WithoutCancel(ctx), opts, j, &res); rbErr != nil {
+			err = errors.Join(err, rbErr)
+		}
+	}
+	if jErr := j.finish(res, err); jErr != nil {
+		err = errors.Join(err, jErr)
+	}
+	return res, err
+}
+
+func createFeatureEnvironment(ctx context.Context, opts Options, j *journal, res *Result, resumed bool) error {
+	r := runner{onEvent: opts.OnEvent, res: res, journal: j}
+	gitInfo := opts.Parent.GitProviderDetails
+
+	err := r.run(StepResolveBaseCommit, fmt.Sprintf("Resolving head of %s", gitInfo.BranchName), func() error {
+		id, err := opts.Git.ResolveBranch(ctx, gitInfo.BranchName)
+		if err != nil {
+			return fmt.Errorf("getting dev branch commit: %w", err)
+		}
+		res.BaseCommitId = id
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.run(StepCreateBranch, fmt.Sprintf("Creating branch %s", opts.BranchName), func() error {
+		err := opts.Git.CreateBranch(ctx, opts.BranchName, res.BaseCommitId)
+		if resumed && gitprovider.IsBranchAlreadyExists(err) {
+			// The interrupted run may have created it; adopt it if it is still at the base.
+			if head, headErr := opts.Git.ResolveBranch(ctx, opts.BranchName); headErr == nil && head == res.BaseCommitId {
+				r.emit(StepCreateBranch, EventStepProgress, "Branch already created", 100, nil)
+				return nil
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("creating feature branch: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.run(StepCreateWorkspace, fmt.Sprintf("Creating workspace %s", opts.WorkspaceName), func() error {
+		req := fabric.CreateWorkspaceRequest{
+			DisplayName: opts.WorkspaceName,
+			Description: "Feature workspace for " + opts.BranchName + " (Parent: " + opts.Parent.DisplayName + ")",
+			CapacityId:  opts.CapacityId,
+		}
+		ws, err := opts.Fabric.CreateWorkspace(ctx, req)
+		if resumed && fabric.IsErrorCode(err, fabric.ErrorCodeWorkspaceNameAlreadyExists) {
+			if existing, findErr := findCreatedWorkspace(ctx, opts.Fabric, req); findErr != nil {
+				return findErr
+			} else if existing != nil {
+				r.emit(StepCreateWorkspace, EventStepProgress, "Workspace already created", 100, nil)
+				ws, err = existing, nil
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("creating workspace: %w", err)
+		}
+		res.Workspace = ws
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	wsId := res.Workspace.Id
+
+	err = r.run(StepConnectGit, "Connecting workspace to git", func() error {
+		newGitInfo := *gitInfo
+		newGitInfo.BranchName = opts.BranchName
+		req := fabric.ConnectToGitRequest{GitProviderDetails: &newGitInfo}
+		if gitInfo.GitProviderType == fabric.GitProviderGitHub {
+			// GitHub connections need explicit credentials; reuse the parent's connection.
+			creds, err := opts.Fabric.GetMyGitCredentials(ctx, opts.Parent.Id)
+			if err != nil {
+				return fmt.Errorf("getting parent git credentials: %w", err)
+			}
+			if creds.Source != fabric.GitCredentialsConfiguredConnection || creds.ConnectionId == "" {
+				return fmt.Errorf("parent workspace %q has no configured GitHub connection", opts.Parent.DisplayName)
+			}
+			req.MyGitCredentials = &fabric.GitCredentials{Source: creds.Source, ConnectionId: creds.ConnectionId}
+		}
+		if err := opts.Fabric.ConnectWorkspaceToGit(ctx, wsId, req); err != nil {
+			return fmt.Errorf("connecting git: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.run(StepInitializeGit, "Initializing git connection", func() error {
+		gitInit, err := opts.Fabric.InitializeGitConnection(ctx, wsId, fabric.InitializeGitConnectionRequest{
+			InitializationStrategy: fabric.InitializationStrategyPreferRemote,
+		})
+		if err != nil {
+			return fmt.Errorf("initializing git connection: %w", err)
+		}
+		res.GitInit = gitInit
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.run(StepUpdateFromGit, "Updating workspace from git", func() error {
+		// A resumed run may already have started the update, in which case we keep polling it.
+		opId := res.OperationId
+		if opId == "" {
+			gitInit := res.GitInit
+			if gitInit == nil {
+				// Journals written before the initialize response was recorded.
+				status, err := opts.Fabric.GetGitStatus(ctx, wsId)
+				if err != nil {
+					return fmt.Errorf("getting git status: %w", err)
+				}
+				gitInit = &fabric.InitializeGitConnectionResponse{
+					RequiredAction:   fabric.RequiredActionUpdateFromGit,
+					WorkspaceHead:    status.WorkspaceHead,
+					RemoteCommitHash: status.RemoteCommitHash,
+				}
+			}
+
+			switch gitInit.RequiredAction {
+			case fabric.RequiredActionNone:
+				r.emit(StepUpdateFromGit, EventStepProgress, "Workspace already up to date", 100, nil)
+				return nil
+			case fabric.RequiredActionCommitToGit:
+				return fmt.Errorf("workspace has changes that must be committed to git before it can be updated")
+			}
+
+			var err error
+			opId, err = opts.Fabric.UpdateWorkspaceFromGit(ctx, wsId, gitInit.WorkspaceHead, gitInit.RemoteCommitHash)
+			if err != nil {
+				return fmt.Errorf("updating from git: %w", err)
+			}
+			if opId == "" {
+				return nil
+			}
+			res.OperationId = opId
+			if err := r.checkpoint(); err != nil {
+				return err
+			}
+		}
+
+		_, err := opts.Fabric.WaitForOperation(ctx, opId, &fabric.WaitOptions{
+			OnProgress: func(status *fabric.OperationStatus) {
+				r.emit(StepUpdateFromGit, EventStepProgress, status.Status, status.PercentComplete, nil)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("git sync failed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Items synced from git still point at the parent's Lakehouses; point them at the
+	// feature workspace's own.
+	err = r.run(StepRebindItems, "Rebinding lakehouses", func() error {
+		mapping, err := mapItems(ctx, opts.Fabric, opts.Parent.Id, wsId)
+		if err != nil {
+			return err
+		}
+		res.ItemMapping = mapping
+		return rebindItems(ctx, opts.Fabric, wsId, mapping, r.itemProgress(StepRebindItems))
+	})
+	if err != nil || opts.Parameters == nil {
+		return err
+	}
+
+	return r.run(StepApplyParameters, fmt.Sprintf("Applying %s parameters", opts.Environment), func() error {
+		return applyParameters(ctx, opts.Fabric, *res.Workspace, opts.Parameters, opts.Environment, r.itemProgress(StepApplyParameters))
+	})
+}
+
+// findCreatedWorkspace returns the workspace an interrupted run created for req, recognised by
+// its name and description, or nil if there is none.
+func findCreatedWorkspace(ctx context.Context, api fabric.API, req fabric.CreateWorkspaceRequest) (*fabric.Workspace, error) {
+	for ws, err := range api.Workspaces(ctx) {
+		if err != nil {
+			return nil, fmt.Errorf("listing workspaces: %w", err)
+		}
+		if ws.DisplayName == req.DisplayName && ws.Description == req.Description {
+			return &ws, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,384 @@
+package workflow_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/amaliebjorgen/fabricant/pkg/fabric"
+	"github.com/amaliebjorgen/fabricant/pkg/fabric/fabrictest"
+	"github.com/amaliebjorgen/fabricant/pkg/workflow"
+)
+
+const (
+	org     = "contoso"
+	project = "data"
+	repo    = "fabric"
+	branch  = "feature/orders"
+)
+
+// fixture is a fake server with a dev workspace connected to main, holding a Lakehouse and a
+// notebook that uses it.
+type fixture struct {
+	srv       *fabrictest.Server
+	parent    fabric.Workspace
+	lakehouse fabric.Item
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	srv := fabrictest.NewServer()
+	t.Cleanup(srv.Close)
+
+	srv.AddBranch(org, project, repo, "main", "c0ffee")
+	parent := srv.AddWorkspace(fabric.Workspace{DisplayName: "Dev", CapacityId: "cap-1"})
+	lh := srv.AddItem(parent.Id, fabric.Item{DisplayName: "Orders", Type: fabric.ItemTypeLakehouse}, nil)
+	srv.AddItem(parent.Id, fabric.Item{DisplayName: "Load orders", Type: fabric.ItemTypeNotebook}, map[string]string{
+		"notebook-content.py": `# META "default_lakehouse": "` + lh.Id + `", "default_lakehouse_workspace_id": "` + parent.Id + `"`,
+	})
+	srv.ConnectGit(parent.Id, fabric.GitProviderDetails{
+		GitProviderType:  fabric.GitProviderAzureDevOps,
+		OrganizationName: org,
+		ProjectName:      project,
+		RepositoryName:   repo,
+		BranchName:       "main",
+	})
+	parent, _ = srv.Workspace(parent.Id)
+	return &fixture{srv: srv, parent: parent, lakehouse: lh}
+}
+
+func (f *fixture) options() workflow.Options {
+	parent := f.parent
+	return workflow.Options{
+		Fabric:        f.srv.FabricClient(),
+		DevOps:        f.srv.DevOpsClient(),
+		Parent:        &parent,
+		BranchName:    branch,
+		WorkspaceName: "Feature - orders",
+	}
+}
+
+// featureWorkspaces returns the workspaces other than the parent.
+func (f *fixture) featureWorkspaces() []fabric.Workspace {
+	return slices.DeleteFunc(f.srv.Workspaces(), func(ws fabric.Workspace) bool { return ws.Id == f.parent.Id })
+}
+
+// checkEnvironment verifies the feature workspace is on the branch and its notebook uses the
+// workspace's own Lakehouse.
+func (f *fixture) checkEnvironment(t *testing.T, res workflow.Result) {
+	t.Helper()
+	if got := f.srv.Branches(org, project, repo)[branch]; got != "c0ffee" {
+		t.Errorf("branch %s points at %q, want c0ffee", branch, got)
+	}
+	wss := f.featureWorkspaces()
+	if len(wss) != 1 {
+		t.Fatalf("got %d feature workspaces, want 1", len(wss))
+	}
+	ws := wss[0]
+	if res.Workspace == nil || res.Workspace.Id != ws.Id {
+		t.Fatalf("result workspace = %+v, want %s", res.Workspace, ws.Id)
+	}
+	if ws.GitProviderDetails == nil || ws.GitProviderDetails.BranchName != branch {
+		t.Errorf("workspace git connection = %+v, want branch %s", ws.GitProviderDetails, branch)
+	}
+
+	var lakehouseId, notebookId string
+	for _, it := range f.srv.Items(ws.Id) {
+		switch it.Type {
+		case fabric.ItemTypeLakehouse:
+			lakehouseId = it.Id
+		case fabric.ItemTypeNotebook:
+			notebookId = it.Id
+		}
+	}
+	if lakehouseId == "" || notebookId == "" {
+		t.Fatalf("feature workspace items = %+v, want a Lakehouse and a Notebook", f.srv.Items(ws.Id))
+	}
+	if res.ItemMapping[strings.ToLower(f.lakehouse.Id)] != lakehouseId {
+		t.Errorf("item mapping = %v, want %s -> %s", res.ItemMapping, f.lakehouse.Id, lakehouseId)
+	}
+	parts, _ := f.srv.ItemDefinition(ws.Id, notebookId)
+	content := parts["notebook-content.py"]
+	if !strings.Contains(content, lakehouseId) || !strings.Contains(content, ws.Id) {
+		t.Errorf("notebook was not rebound: %s", content)
+	}
+	if strings.Contains(content, f.lakehouse.Id) || strings.Contains(content, f.parent.Id) {
+		t.Errorf("notebook still references the parent: %s", content)
+	}
+}
+
+func TestCreateFeatureEnvironment(t *testing.T) {
+	f := newFixture(t)
+	opts := f.options()
+	var started []workflow.Step
+	opts.OnEvent = func(ev workflow.Event) {
+		if ev.Kind == workflow.EventStepStarted {
+			started = append(started, ev.Step)
+		}
+	}
+
+	res, err := workflow.CreateFeatureEnvironment(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("CreateFeatureEnvironment: %v", err)
+	}
+	f.checkEnvironment(t, res)
+
+	want := []workflow.Step{
+		workflow.StepResolveBaseCommit, workflow.StepCreateBranch, workflow.StepCreateWorkspace,
+		workflow.StepConnectGit, workflow.StepInitializeGit, workflow.StepUpdateFromGit, workflow.StepRebindItems,
+	}
+	if !slices.Equal(started, want) {
+		t.Errorf("started steps = %v, want %v", started, want)
+	}
+	if !slices.Equal(res.Completed, want) {
+		t.Errorf("completed steps = %v, want %v", res.Completed, want)
+	}
+}
+
+func TestCreateFeatureEnvironmentRollsBackFailedUpdate(t *testing.T) {
+	f := newFixture(t)
+	f.srv.Fail(fabrictest.Fault{Path: "/v1/workspaces/*/git/updateFromGit", Operation: true, ErrorCode: "GitSyncFailed", Message: "sync failed"})
+	opts := f.options()
+	opts.RollbackOnFailure = true
+	opts.Store = workflow.NewRunStore(t.TempDir())
+
+	res, err := workflow.CreateFeatureEnvironment(context.Background(), opts)
+	if !fabric.IsErrorCode(err, "GitSyncFailed") {
+		t.Fatalf("CreateFeatureEnvironment error = %v, want GitSyncFailed", err)
+	}
+	if res.NeedsRollback() {
+		t.Errorf("result still needs rollback, completed %v", res.Completed)
+	}
+	for _, step := range []workflow.Step{workflow.StepDeleteWorkspace, workflow.StepDeleteBranch} {
+		if !res.HasCompleted(step) {
+			t.Errorf("%s did not complete, completed %v", step, res.Completed)
+		}
+	}
+	if wss := f.featureWorkspaces(); len(wss) != 0 {
+		t.Errorf("feature workspaces left behind: %+v", wss)
+	}
+	if _, ok := f.srv.Branches(org, project, repo)[branch]; ok {
+		t.Errorf("branch %s left behind", branch)
+	}
+
+	run, err := opts.Store.Load(res.RunId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != workflow.RunRolledBack || run.Unfinished() {
+		t.Errorf("run status = %s, unfinished %v; want %s and finished", run.Status, run.Unfinished(), workflow.RunRolledBack)
+	}
+}
+
+func TestResumeFeatureEnvironmentAfterFailedConnect(t *testing.T) {
+	f := newFixture(t)
+	f.srv.Fail(fabrictest.Fault{Method: "POST", Path: "/v1/workspaces/*/git/connect", ErrorCode: "GitProviderUnavailable", Times: 1})
+	opts := f.options()
+	opts.Store = workflow.NewRunStore(t.TempDir())
+	ctx := context.Background()
+
+	res, err := workflow.CreateFeatureEnvironment(ctx, opts)
+	if !fabric.IsErrorCode(err, "GitProviderUnavailable") {
+		t.Fatalf("CreateFeatureEnvironment error = %v, want GitProviderUnavailable", err)
+	}
+	runs, err := opts.Store.Unfinished()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Id != res.RunId {
+		t.Fatalf("unfinished runs = %+v, want %s", runs, res.RunId)
+	}
+	if got := runs[0].LastStep(); got != workflow.StepCreateWorkspace {
+		t.Errorf("last step = %s, want %s", got, workflow.StepCreateWorkspace)
+	}
+
+	var skipped []workflow.Step
+	resumeOpts := workflow.Options{
+		Fabric: f.srv.FabricClient(),
+		DevOps: f.srv.DevOpsClient(),
+		Store:  opts.Store,
+		OnEvent: func(ev workflow.Event) {
+			if ev.Kind == workflow.EventStepStarted && ev.Skipped {
+				skipped = append(skipped, ev.Step)
+			}
+		},
+	}
+	res, err = workflow.ResumeFeatureEnvironment(ctx, resumeOpts, runs[0])
+	if err != nil {
+		t.Fatalf("ResumeFeatureEnvironment: %v", err)
+	}
+	f.checkEnvironment(t, res)
+
+	want := []workflow.Step{workflow.StepResolveBaseCommit, workflow.StepCreateBranch, workflow.StepCreateWorkspace}
+	if !slices.Equal(skipped, want) {
+		t.Errorf("skipped steps = %v, want %v", skipped, want)
+	}
+	run, err := opts.Store.Load(res.RunId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != workflow.RunSucceeded {
+		t.Errorf("run status = %s, want %s", run.Status, workflow.RunSucceeded)
+	}
+}
+
+func TestResumeAdoptsResourcesCreatedBeforeInterruption(t *testing.T) {
+	f := newFixture(t)
+	f.srv.Fail(fabrictest.Fault{Method: "POST", Path: "/v1/workspaces/*/git/connect", ErrorCode: "GitProviderUnavailable", Times: 1})
+	opts := f.options()
+	opts.Store = workflow.NewRunStore(t.TempDir())
+	ctx := context.Background()
+
+	res, err := workflow.CreateFeatureEnvironment(ctx, opts)
+	if err == nil {
+		t.Fatal("CreateFeatureEnvironment succeeded, want a connect failure")
+	}
+
+	// Pretend the process died after creating the branch and workspace but before journaling them.
+	run, err := opts.Store.Load(res.RunId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.Result.Completed = []workflow.Step{workflow.StepResolveBaseCommit}
+	run.Result.Workspace = nil
+	if err := opts.Store.Save(run); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = workflow.ResumeFeatureEnvironment(ctx, opts, run)
+	if err != nil {
+		t.Fatalf("ResumeFeatureEnvironment: %v", err)
+	}
+	f.checkEnvironment(t, res)
+}
+
+func TestResumeRefusesPartiallyRolledBackRun(t *testing.T) {
+	f := newFixture(t)
+	f.srv.Fail(fabrictest.Fault{Method: "POST", Path: "/v1/workspaces/*/git/connect", ErrorCode: "GitProviderUnavailable"})
+	opts := f.options()
+	opts.Store = workflow.NewRunStore(t.TempDir())
+	ctx := context.Background()
+
+	res, err := workflow.CreateFeatureEnvironment(ctx, opts)
+	if err == nil {
+		t.Fatal("CreateFeatureEnvironment succeeded, want a connect failure")
+	}
+
+	// Deleting the workspace succeeds but deleting the branch does not.
+	f.srv.Fail(fabrictest.Fault{Method: "POST", Path: "/devops/*/*/_apis/git/repositories/*/refs", ErrorCode: "ServiceUnavailable", Times: 1})
+	if err := workflow.Rollback(ctx, opts, &res); err == nil {
+		t.Fatal("Rollback succeeded, want the branch deletion to fail")
+	}
+	run, err := opts.Store.Load(res.RunId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Unfinished() || !run.PartiallyRolledBack() {
+		t.Fatalf("run unfinished %v, partially rolled back %v; want only partially rolled back", run.Unfinished(), run.PartiallyRolledBack())
+	}
+	if runs, _ := opts.Store.Unfinished(); len(runs) != 0 {
+		t.Errorf("unfinished runs = %+v, want none", runs)
+	}
+	if _, err := workflow.ResumeFeatureEnvironment(ctx, opts, run); err == nil {
+		t.Fatal("ResumeFeatureEnvironment resumed a partially rolled back run")
+	}
+
+	if _, err := workflow.RollbackRun(ctx, opts, run); err != nil {
+		t.Fatalf("RollbackRun: %v", err)
+	}
+	if _, ok := f.srv.Branches(org, project, repo)[branch]; ok {
+		t.Errorf("branch %s left behind", branch)
+	}
+	if run, _ = opts.Store.Load(res.RunId); run.Status != workflow.RunRolledBack {
+		t.Errorf("run status = %s, want %s", run.Status, workflow.RunRolledBack)
+	}
+}
+
+func TestRollbackTreatsMissingResourcesAsDeleted(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove func(ctx context.Context, f *fixture, res workflow.Result) error
+	}{
+		{
+			name: "workspace",
+			remove: func(ctx context.Context, f *fixture, res workflow.Result) error {
+				return f.srv.FabricClient().DeleteWorkspace(ctx, res.Workspace.Id)
+			},
+		},
+		{
+			name: "branch",
+			remove: func(ctx context.Context, f *fixture, res workflow.Result) error {
+				return f.srv.DevOpsClient().DeleteBranch(ctx, org, project, repo, branch)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.srv.Fail(fabrictest.Fault{Method: "POST", Path: "/v1/workspaces/*/git/connect", ErrorCode: "GitProviderUnavailable"})
+			opts := f.options()
+			opts.Store = workflow.NewRunStore(t.TempDir())
+			ctx := context.Background()
+
+			res, err := workflow.CreateFeatureEnvironment(ctx, opts)
+			if err == nil {
+				t.Fatal("CreateFeatureEnvironment succeeded, want a connect failure")
+			}
+			if err := tt.remove(ctx, f, res); err != nil {
+				t.Fatalf("removing the %s by hand: %v", tt.name, err)
+			}
+
+			if err := workflow.Rollback(ctx, opts, &res); err != nil {
+				t.Fatalf("Rollback: %v", err)
+			}
+			if wss := f.featureWorkspaces(); len(wss) != 0 {
+				t.Errorf("feature workspaces left behind: %+v", wss)
+			}
+			if _, ok := f.srv.Branches(org, project, repo)[branch]; ok {
+				t.Errorf("branch %s left behind", branch)
+			}
+			run, err := opts.Store.Load(res.RunId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.Status != workflow.RunRolledBack {
+				t.Errorf("run status = %s, want %s", run.Status, workflow.RunRolledBack)
+			}
+		})
+	}
+}
+
+func TestCreateFeatureEnvironmentRollsBackWhenCancelled(t *testing.T) {
+	f := newFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := f.options()
+	opts.RollbackOnFailure = true
+	opts.Store = workflow.NewRunStore(t.TempDir())
+	opts.OnEvent = func(ev workflow.Event) {
+		// Stands in for Ctrl+C arriving while git is being connected.
+		if ev.Step == workflow.StepConnectGit && ev.Kind == workflow.EventStepStarted {
+			cancel()
+		}
+	}
+
+	res, err := workflow.CreateFeatureEnvironment(ctx, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CreateFeatureEnvironment error = %v, want context.Canceled", err)
+	}
+	if res.NeedsRollback() {
+		t.Errorf("result still needs rollback, completed %v", res.Completed)
+	}
+	if wss := f.featureWorkspaces(); len(wss) != 0 {
+		t.Errorf("feature workspaces left behind: %+v", wss)
+	}
+	run, err := opts.Store.Load(res.RunId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != workflow.RunRolledBack || run.Error == "" {
+		t.Errorf("run status = %s, error %q; want %s with the cancellation recorded", run.Status, run.Error, workflow.RunRolledBack)
+	}
+}